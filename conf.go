@@ -1,7 +1,9 @@
 package conf
 
 import (
-	"os"
+	"io"
+	"reflect"
+	"strings"
 
 	"github.com/nofeaturesonlybugs/conf/parser"
 	"github.com/nofeaturesonlybugs/errors"
@@ -13,39 +15,107 @@ type Conf struct {
 	parsed parser.Parsed
 }
 
+// Loader creates Conf types with a specific Parser.  Start from parser.DefaultParser to enable optional
+// parser features:
+//	loader := conf.Loader{Parser: parser.DefaultParser}
+//	loader.Parser.Interpolate = true
+//	loader.Parser.Include = "include"
+//	c, err := loader.File("app.conf")
+type Loader struct {
+	Parser parser.Parser
+	// Merge determines how Load merges sources.
+	Merge MergeOptions
+	// Skip returns true for files that Dir and Glob should ignore; SkipBackups is used when Skip is nil.
+	Skip func(name string) bool
+}
+
 // File returns a Conf type by reading and parsing the given file.
-func File(file string) (*Conf, error) {
-	handle, err := os.Open(file)
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func (me Loader) File(file string) (*Conf, error) {
+	parsed, err := me.Parser.ParseFile(file)
 	if err != nil {
-		return nil, errors.Go(err)
+		return nil, err
 	}
-	defer handle.Close()
 	//
-	parsed, err := parser.DefaultParser.ParseReader(handle)
+	return &Conf{parsed}, nil
+}
+
+// Reader returns a Conf type by reading and parsing the given reader.
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func (me Loader) Reader(r io.Reader) (*Conf, error) {
+	parsed, err := me.Parser.ParseReader(r)
 	if err != nil {
-		return nil, errors.Go(err)
+		return nil, err
 	}
 	//
 	return &Conf{parsed}, nil
 }
 
 // String returns a Conf type by parsing the given string of configuration data.
-func String(s string) (*Conf, error) {
-	parsed, err := parser.DefaultParser.Parse(s)
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func (me Loader) String(s string) (*Conf, error) {
+	parsed, err := me.Parser.Parse(s)
 	if err != nil {
-		return nil, errors.Go(err)
+		return nil, err
 	}
 	//
 	return &Conf{parsed}, nil
 }
 
-// fill populates target either ByTag or ByFieldName as determined by tag == "".
-func (me *Conf) fill(target interface{}, tag string) error {
+// File returns a Conf type by reading and parsing the given file with parser.DefaultParser.
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func File(file string) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.File(file)
+}
+
+// String returns a Conf type by parsing the given string of configuration data with parser.DefaultParser.
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func String(s string) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.String(s)
+}
+
+// FillOptions configures Conf.FillWith.
+type FillOptions struct {
+	// Tag is the struct tag that names keys and sections; when empty the field names are used.
+	Tag string
+
+	// Env enables the environment variable overlay; a field whose environment variable is set receives
+	// the variable's value instead of the value from the configuration.
+	Env bool
+
+	// EnvPrefix is prepended to automatically named environment variables.
+	EnvPrefix string
+
+	// EnvTag is the struct tag that explicitly names a field's environment variable; defaults to "env".
+	EnvTag string
+
+	// EnvSeparator splits environment variables for slice fields; defaults to ",".
+	EnvSeparator string
+
+	// DefaultTag is the struct tag that provides a value for keys missing from the configuration;
+	// defaults to "default".
+	DefaultTag string
+
+	// DefaultSeparator splits default values for slice fields; defaults to ",".
+	DefaultSeparator string
+
+	// Strict causes keys and sections that are not consumed by the target to be returned as *UnusedError.
+	Strict bool
+}
+
+// fill populates target either ByTag or ByFieldName as determined by opts.Tag == "".
+func (me *Conf) fill(target interface{}, opts FillOptions) error {
 	if me == nil {
 		return errors.NilReceiver()
 	}
 	//
 	value := set.V(target)
+	tag := opts.Tag
 	//
 	var fields []set.Field
 	if tag == "" {
@@ -54,39 +124,133 @@ func (me *Conf) fill(target interface{}, tag string) error {
 		fields = value.FieldsByTag(tag)
 	}
 	//
-	m := me.parsed.Map()
+	m := me.sections()
+	if opts.Env {
+		overlayEnv(m, fields, opts)
+	}
+	// Sections created by defaults do not satisfy required sections.
+	present := map[string]bool{}
+	for name, sections := range m {
+		present[name] = len(sections) > 0
+	}
+	applyDefaults(m, fields, opts)
+	if err := checkRequired(m, present, fields, tag); err != nil {
+		return err
+	} else if opts.Strict {
+		if unused := me.unused(fields, tag); len(unused) > 0 {
+			return &UnusedError{Unused: unused}
+		}
+	}
 	globalSection, getter := set.MapGetter(m[""][len(m[""])-1]), set.MapGetter(m)
-	scalars := map[string]set.Getter{}
+	scalars, maps := map[string]set.Getter{}, map[string]bool{}
 	for _, field := range fields {
 		if field.Value.IsScalar || (field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
-			if tag == "" {
-				scalars[field.Field.Name] = globalSection
-			} else {
-				scalars[field.TagValue] = globalSection
-			}
+			scalars[fieldName(field, tag)] = globalSection
+		} else if isMap(field) {
+			maps[fieldName(field, tag)] = true
 		}
 	}
 	//
-	fn := set.GetterFunc(func(name string) interface{} {
+	fn := optionGetter(set.GetterFunc(func(name string) interface{} {
 		if scalarGetter, ok := scalars[name]; ok {
 			return scalarGetter.Get(name)
+		} else if maps[name] || name == subsectionKey {
+			// Maps are filled below and the global section has no subsection.
+			return nil
 		}
 		return getter.Get(name)
+	}))
+	var err error
+	if tag == "" {
+		err = value.Fill(fn)
+	} else {
+		err = value.FillByTag(tag, fn)
+	}
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if isMap(field) {
+			if err = fillMap(m, field, fieldName(field, tag), tag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName returns the key or section name for field; options following a comma in the tag are removed.
+func fieldName(field set.Field, tag string) string {
+	if tag == "" {
+		return field.Field.Name
+	}
+	return tagName(field.TagValue)
+}
+
+// tagName returns the name from a struct tag value of the form name,option,option.
+func tagName(value string) string {
+	if n := strings.IndexByte(value, ','); n != -1 {
+		return value[:n]
+	}
+	return value
+}
+
+// tagOption returns true if the struct tag value of the form name,option,option contains option.
+func tagOption(value string, option string) bool {
+	for _, str := range strings.Split(value, ",")[1:] {
+		if strings.TrimSpace(str) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// optionGetter wraps getter so names passed to it and to any Getter it returns have their struct tag
+// options removed; names with the name option get the subsection.
+func optionGetter(getter set.Getter) set.Getter {
+	return set.GetterFunc(func(name string) interface{} {
+		key := tagName(name)
+		if tagOption(name, "name") {
+			key = subsectionKey
+		}
+		switch got := getter.Get(key).(type) {
+		case set.Getter:
+			return optionGetter(got)
+		case []set.Getter:
+			rv := make([]set.Getter, len(got))
+			for k, g := range got {
+				rv[k] = optionGetter(g)
+			}
+			return rv
+		default:
+			return got
+		}
 	})
+}
+
+// typeFields returns the fields of a new value of type T either ByTag or ByFieldName as determined by tag == "".
+func typeFields(T reflect.Type, tag string) []set.Field {
+	value := set.V(reflect.New(T))
 	if tag == "" {
-		return value.Fill(fn)
+		return value.Fields()
 	}
-	return value.FillByTag(tag, fn)
+	return value.FieldsByTag(tag)
 }
 
 // Fill places the data from the configuration into the given target which should be
 // a pointer to struct.
 func (me *Conf) Fill(target interface{}) error {
-	return me.fill(target, "")
+	return me.fill(target, FillOptions{})
 }
 
 // FillByTag places the data from the configuration into the given target which should be
 // a pointer to struct.
 func (me *Conf) FillByTag(tag string, target interface{}) error {
-	return me.fill(target, tag)
+	return me.fill(target, FillOptions{Tag: tag})
+}
+
+// FillWith places the data from the configuration into the given target which should be
+// a pointer to struct; opts selects the struct tag and the environment variable overlay.
+func (me *Conf) FillWith(target interface{}, opts FillOptions) error {
+	return me.fill(target, opts)
 }
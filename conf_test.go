@@ -1,14 +1,17 @@
 package conf_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
 )
 
 func TestConf_Spaces(t *testing.T) {
@@ -498,11 +501,22 @@ Global = I'm also global!
 		conf, err := conf.File(tmpfile.Name())
 		chk.Error(err)
 		chk.Nil(conf)
+		var syntaxErr *parser.SyntaxError
+		if chk.True(errors.As(err, &syntaxErr)) {
+			chk.Equal(tmpfile.Name(), syntaxErr.File)
+			chk.Equal(2, syntaxErr.Line)
+			chk.Equal("\tGlobal  I'm a global!", syntaxErr.Excerpt)
+		}
 	}
 	{
 		conf, err := conf.String(s)
 		chk.Error(err)
 		chk.Nil(conf)
+		var syntaxErr *parser.SyntaxError
+		if chk.True(errors.As(err, &syntaxErr)) {
+			chk.Equal("", syntaxErr.File)
+			chk.Equal(2, syntaxErr.Line)
+		}
 	}
 }
 
@@ -520,3 +534,65 @@ func TestConf_File_doesNotExist(t *testing.T) {
 	_, err := conf.File("asldjflaksdjflaksjflasjdf")
 	chk.Error(err)
 }
+
+func TestLoader(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+base = /opt/app
+[ paths ]
+logs = ${base}/logs
+`
+	type T struct {
+		Base  string `conf:"base"`
+		Paths struct {
+			Logs string `conf:"logs"`
+		} `conf:"paths"`
+	}
+	loader := conf.Loader{Parser: parser.DefaultParser}
+	loader.Parser.Interpolate = true
+	{
+		c, err := loader.String(s)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("/opt/app", t.Base)
+		chk.Equal("/opt/app/logs", t.Paths.Logs)
+	}
+	{
+		c, err := loader.Reader(strings.NewReader(s))
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("/opt/app/logs", t.Paths.Logs)
+	}
+	{
+		tmpfile, err := ioutil.TempFile("", "gotest")
+		chk.NoError(err)
+		defer os.Remove(tmpfile.Name())
+		_, err = tmpfile.Write([]byte(s))
+		chk.NoError(err)
+		chk.NoError(tmpfile.Close())
+		c, err := loader.File(tmpfile.Name())
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("/opt/app/logs", t.Paths.Logs)
+	}
+	{ // Errors
+		_, err := loader.String("a = ${b}\n")
+		var syntaxErr *parser.SyntaxError
+		chk.True(errors.As(err, &syntaxErr))
+		_, err = loader.Reader(strings.NewReader("a = ${b}\n"))
+		chk.True(errors.As(err, &syntaxErr))
+		_, err = loader.File("asldjflaksdjflaksjflasjdf")
+		chk.Error(err)
+	}
+	{ // Without the loader the references are kept.
+		c, err := conf.String(s)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("${base}/logs", t.Paths.Logs)
+	}
+}
@@ -0,0 +1,78 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// applyDefaults adds the default values of fields to m for keys that are missing.
+func applyDefaults(m map[string][]map[string][]string, fields []set.Field, opts FillOptions) {
+	if opts.DefaultTag == "" {
+		opts.DefaultTag = "default"
+	}
+	if opts.DefaultSeparator == "" {
+		opts.DefaultSeparator = ","
+	}
+	//
+	defaultSection(m[""][len(m[""])-1], fields, opts)
+	for _, field := range fields {
+		var T reflect.Type
+		if isStructMap(field) {
+			// Each section of a map of structs receives its defaults.
+			sectionFields := typeFields(field.Value.ElemTypeInfo.Type, opts.Tag)
+			for _, entry := range mapSections(m, fieldName(field, opts.Tag)) {
+				defaultSection(entry.section, sectionFields, opts)
+			}
+			continue
+		} else if field.Value.IsStruct {
+			T = field.Value.Type
+		} else if field.Value.IsSlice && field.Value.ElemTypeInfo.IsStruct {
+			T = field.Value.ElemTypeInfo.Type
+		} else {
+			continue
+		}
+		sectionFields := typeFields(T, opts.Tag)
+		name := fieldName(field, opts.Tag)
+		if len(m[name]) == 0 && field.Value.IsStruct {
+			// A missing section still receives its defaults.
+			section := map[string][]string{}
+			if defaultSection(section, sectionFields, opts) {
+				m[name] = append(m[name], section)
+			}
+			continue
+		}
+		for _, section := range m[name] {
+			defaultSection(section, sectionFields, opts)
+		}
+	}
+}
+
+// defaultSection adds the default values of the scalar and scalar slice fields to section for keys that are
+// missing; it returns true if any default was added.
+func defaultSection(section map[string][]string, fields []set.Field, opts FillOptions) bool {
+	rv := false
+	for _, field := range fields {
+		if !field.Value.IsScalar && !(field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
+			continue
+		} else if isSubsection(field, opts.Tag) {
+			continue
+		}
+		str, ok := field.Field.Tag.Lookup(opts.DefaultTag)
+		if !ok {
+			continue
+		}
+		key := fieldName(field, opts.Tag)
+		if _, ok := section[key]; ok {
+			continue
+		}
+		if field.Value.IsSlice {
+			section[key] = strings.Split(str, opts.DefaultSeparator)
+		} else {
+			section[key] = []string{str}
+		}
+		rv = true
+	}
+	return rv
+}
@@ -0,0 +1,92 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+func TestConf_FillDefaults(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+name = from file
+empty =
+
+[server]
+listen = :80
+
+[server]
+timeout = 5
+`
+	type T struct {
+		Name  string   `conf:"name" default:"unused"`
+		Empty string   `conf:"empty" default:"unused"`
+		Port  int      `conf:"port" default:"8080"`
+		Hosts []string `conf:"hosts" default:"a,b"`
+		//
+		Database struct {
+			Host string `conf:"host" default:"localhost"`
+			User string `conf:"user"`
+		} `conf:"database"`
+		//
+		Servers []struct {
+			Listen  string `conf:"listen" default:":8000"`
+			Timeout int    `conf:"timeout" default:"30"`
+		} `conf:"server"`
+		//
+		Missing []struct {
+			Key string `conf:"key" default:"value"`
+		} `conf:"missing"`
+	}
+	c, err := conf.String(s)
+	chk.NoError(err)
+	{
+		var t T
+		err = c.FillByTag("conf", &t)
+		chk.NoError(err)
+		chk.Equal("from file", t.Name)
+		chk.Equal("", t.Empty)
+		chk.Equal(8080, t.Port)
+		chk.Equal([]string{"a", "b"}, t.Hosts)
+		chk.Equal("localhost", t.Database.Host)
+		chk.Equal("", t.Database.User)
+		chk.Equal(2, len(t.Servers))
+		chk.Equal(":80", t.Servers[0].Listen)
+		chk.Equal(30, t.Servers[0].Timeout)
+		chk.Equal(":8000", t.Servers[1].Listen)
+		chk.Equal(5, t.Servers[1].Timeout)
+		chk.Equal(0, len(t.Missing))
+	}
+	{
+		// Companion tag and separator.
+		type U struct {
+			Port  int      `conf:"port" fallback:"9090"`
+			Hosts []string `conf:"hosts" fallback:"x|y"`
+		}
+		var u U
+		err = c.FillWith(&u, conf.FillOptions{Tag: "conf", DefaultTag: "fallback", DefaultSeparator: "|"})
+		chk.NoError(err)
+		chk.Equal(9090, u.Port)
+		chk.Equal([]string{"x", "y"}, u.Hosts)
+	}
+	{
+		// Environment variables take precedence over defaults.
+		defer setenv(map[string]string{"PORT": "7070"})()
+		var t T
+		err = c.FillWith(&t, conf.FillOptions{Tag: "conf", Env: true})
+		chk.NoError(err)
+		chk.Equal(7070, t.Port)
+	}
+	{
+		// Invalid defaults are conversion errors.
+		type U struct {
+			Port int `conf:"port" default:"eighty"`
+		}
+		var u U
+		err = c.FillByTag("conf", &u)
+		chk.Error(err)
+	}
+}
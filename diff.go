@@ -0,0 +1,291 @@
+package conf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+// ChangeType describes a Change.
+type ChangeType int
+
+// Enums for ChangeType.
+const (
+	ChangeAdded ChangeType = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+// String returns the ChangeType as a string.
+func (me ChangeType) String() string {
+	switch me {
+	case ChangeAdded:
+		return "Added"
+	case ChangeRemoved:
+		return "Removed"
+	case ChangeModified:
+		return "Modified"
+	}
+	return fmt.Sprintf("Unknown %T= %v", me, int(me))
+}
+
+// Change is a difference between two configurations.
+type Change struct {
+	Type ChangeType
+	// Section is the name of the section; empty for the global section.
+	Section string
+	// Subsection is the subsection named in the section header; empty when the header did not name one.
+	Subsection string
+	// Index is the position of the section among sections with the same name; the position in the new
+	// configuration unless the section was removed.
+	Index int
+	// Key is the name of the key; empty when an entire section was added or removed.
+	Key string
+	// Old and New are the values of the key before and after; Old is nil when the key was added and New is
+	// nil when the key was removed.
+	Old, New []string
+	// OldPosition and NewPosition are where the key or section was defined before and after.
+	OldPosition, NewPosition parser.Position
+}
+
+// Changes is a list of Change ordered by section, index, subsection, and key; a section change comes before the
+// changes to its keys.
+type Changes []Change
+
+// Diff returns the changes required to turn a into b; nil is treated as an empty configuration.
+//
+// Sections with the same name that name a subsection are compared with the section naming the same subsection;
+// other sections with the same name are compared by position among the sections without a subsection.  Sections
+// without a match in a are added and those without a match in b are removed, along with their keys.  Keys whose
+// list of values differ in any way are modified.
+func Diff(a, b *Conf) Changes {
+	var before, after parser.Parsed
+	if a != nil {
+		before = a.parsed
+	}
+	if b != nil {
+		after = b.parsed
+	}
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	//
+	var rv Changes
+	for name := range names {
+		oldBlock, newBlock := before[name], after[name]
+		for _, pair := range sectionPairs(oldBlock, newBlock) {
+			var old, new parser.Section
+			change := Change{Section: name, Index: pair[1]}
+			if pair[0] != -1 {
+				old, change.Subsection = oldBlock.Slice[pair[0]], oldBlock.Subsection(pair[0])
+			}
+			if pair[1] != -1 {
+				new, change.Subsection = newBlock.Slice[pair[1]], newBlock.Subsection(pair[1])
+			} else {
+				change.Index = pair[0]
+			}
+			// The global section always exists; only its keys can change.
+			if name != "" && pair[0] == -1 {
+				change.Type, change.NewPosition = ChangeAdded, sectionPosition(newBlock, pair[1])
+				rv = append(rv, change)
+			} else if name != "" && pair[1] == -1 {
+				change.Type, change.OldPosition = ChangeRemoved, sectionPosition(oldBlock, pair[0])
+				rv = append(rv, change)
+			}
+			rv = append(rv, diffSection(change, old, new)...)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Section != rv[j].Section {
+			return rv[i].Section < rv[j].Section
+		} else if rv[i].Index != rv[j].Index {
+			return rv[i].Index < rv[j].Index
+		} else if rv[i].Subsection != rv[j].Subsection {
+			return rv[i].Subsection < rv[j].Subsection
+		}
+		return rv[i].Key < rv[j].Key
+	})
+	return rv
+}
+
+// sectionPairs returns the indexes of the sections in old and new that are compared by Diff; an index is -1
+// when the section has no match.  The nth section naming a subsection is paired with the nth section naming the
+// same subsection; sections without a subsection are paired in the same way so they are compared by position.
+func sectionPairs(old, new *parser.SectionBlock) [][2]int {
+	type occurrence struct {
+		subsection string
+		n          int
+	}
+	occurrences := func(block *parser.SectionBlock) []occurrence {
+		var rv []occurrence
+		if block != nil {
+			seen := map[string]int{}
+			for k := range block.Slice {
+				subsection := block.Subsection(k)
+				rv = append(rv, occurrence{subsection: subsection, n: seen[subsection]})
+				seen[subsection]++
+			}
+		}
+		return rv
+	}
+	index := map[occurrence]int{}
+	for k, occurrence := range occurrences(new) {
+		index[occurrence] = k
+	}
+	var rv [][2]int
+	matched := map[int]bool{}
+	for k, occurrence := range occurrences(old) {
+		n, ok := index[occurrence]
+		if ok {
+			matched[n] = true
+		} else {
+			n = -1
+		}
+		rv = append(rv, [2]int{k, n})
+	}
+	if new != nil {
+		for k := range new.Slice {
+			if !matched[k] {
+				rv = append(rv, [2]int{-1, k})
+			}
+		}
+	}
+	return rv
+}
+
+// diffSection returns the changes to the keys between old and new; section identifies the section in
+// each Change.
+func diffSection(section Change, old, new parser.Section) Changes {
+	keys := map[string]bool{}
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range new {
+		keys[key] = true
+	}
+	var rv Changes
+	for key := range keys {
+		change := Change{Section: section.Section, Subsection: section.Subsection, Index: section.Index, Key: key}
+		if value, ok := old[key]; ok {
+			change.Old, change.OldPosition = append([]string{}, value.Slice...), valuePosition(value)
+		}
+		if value, ok := new[key]; ok {
+			change.New, change.NewPosition = append([]string{}, value.Slice...), valuePosition(value)
+		}
+		if change.Old == nil {
+			change.Type = ChangeAdded
+		} else if change.New == nil {
+			change.Type = ChangeRemoved
+		} else if !equalStrings(change.Old, change.New) {
+			change.Type = ChangeModified
+		} else {
+			continue
+		}
+		rv = append(rv, change)
+	}
+	return rv
+}
+
+// valuePosition returns the position of the first value in value if it exists.
+func valuePosition(value *parser.Value) parser.Position {
+	if value != nil && len(value.Positions) > 0 {
+		return value.Positions[0]
+	}
+	return parser.Position{}
+}
+
+// String returns a unified-style report of the changes; each section is introduced with @@ and lines are
+// prefixed with - when removed, + when added, or a space when unchanged.
+func (me Changes) String() string {
+	s := &strings.Builder{}
+	heading := ""
+	for _, change := range me {
+		if str := change.heading(); str != heading {
+			heading = str
+			fmt.Fprintf(s, "@@ %v @@\n", heading)
+		}
+		if change.Key == "" {
+			sign := "+"
+			if change.Type == ChangeRemoved {
+				sign = "-"
+			}
+			fmt.Fprintf(s, "%v%v\n", sign, change.header())
+			continue
+		}
+		for _, line := range diffLines(change.Old, change.New) {
+			fmt.Fprintf(s, "%c%v = %v\n", line.sign, change.Key, quoteReport(line.value))
+		}
+	}
+	return s.String()
+}
+
+// heading returns the name of the section containing the change for Changes.String.
+func (me Change) heading() string {
+	if me.Section == "" {
+		return "global"
+	}
+	return fmt.Sprintf("%v #%v", me.header(), me.Index)
+}
+
+// header returns the section header of the section containing the change for Changes.String.
+func (me Change) header() string {
+	if me.Subsection == "" {
+		return fmt.Sprintf("[%v]", me.Section)
+	}
+	return fmt.Sprintf("[%v %q]", me.Section, me.Subsection)
+}
+
+// quoteReport quotes value for Changes.String.
+func quoteReport(value string) string {
+	if str, err := quote(value); err == nil {
+		return str
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// diffLine is a line in the report from Changes.String.
+type diffLine struct {
+	sign  rune
+	value string
+}
+
+// diffLines returns the lines that turn old into new using the longest common subsequence.
+func diffLines(old, new []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of old[i:] and new[j:].
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var rv []diffLine
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		if i < len(old) && j < len(new) && old[i] == new[j] {
+			rv = append(rv, diffLine{' ', old[i]})
+			i, j = i+1, j+1
+		} else if j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]) {
+			rv = append(rv, diffLine{'-', old[i]})
+			i++
+		} else {
+			rv = append(rv, diffLine{'+', new[j]})
+			j++
+		}
+	}
+	return rv
+}
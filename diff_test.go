@@ -0,0 +1,182 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestDiff(t *testing.T) {
+	chk := assert.New(t)
+	//
+	a, err := conf.String(`
+version = 1.0
+debug = true
+
+[server]
+listen = :80
+listen = :443
+
+[server]
+listen = :8080
+
+[database]
+host = localhost
+`)
+	chk.NoError(err)
+	b, err := conf.String(`
+version = 1.1
+name = app
+
+[server]
+listen = :80
+listen = :8443
+listen = :443
+
+[logging]
+level = debug
+`)
+	chk.NoError(err)
+	//
+	changes := conf.Diff(a, b)
+	if chk.Len(changes, 10) {
+		chk.Equal(conf.ChangeRemoved, changes[0].Type)
+		chk.Equal("debug", changes[0].Key)
+		chk.Equal([]string{"true"}, changes[0].Old)
+		chk.Nil(changes[0].New)
+		chk.Equal(3, changes[0].OldPosition.Line)
+		//
+		chk.Equal(conf.ChangeAdded, changes[1].Type)
+		chk.Equal("name", changes[1].Key)
+		chk.Equal([]string{"app"}, changes[1].New)
+		//
+		chk.Equal(conf.ChangeModified, changes[2].Type)
+		chk.Equal("version", changes[2].Key)
+		chk.Equal([]string{"1.0"}, changes[2].Old)
+		chk.Equal([]string{"1.1"}, changes[2].New)
+		//
+		chk.Equal(conf.ChangeRemoved, changes[3].Type)
+		chk.Equal("database", changes[3].Section)
+		chk.Equal("", changes[3].Key)
+		chk.Equal(conf.ChangeRemoved, changes[4].Type)
+		chk.Equal("host", changes[4].Key)
+		//
+		chk.Equal(conf.ChangeAdded, changes[5].Type)
+		chk.Equal("logging", changes[5].Section)
+		chk.Equal(conf.ChangeAdded, changes[6].Type)
+		chk.Equal("level", changes[6].Key)
+		//
+		chk.Equal(conf.ChangeModified, changes[7].Type)
+		chk.Equal("server", changes[7].Section)
+		chk.Equal(0, changes[7].Index)
+		chk.Equal([]string{":80", ":443"}, changes[7].Old)
+		chk.Equal([]string{":80", ":8443", ":443"}, changes[7].New)
+		//
+		chk.Equal(conf.ChangeRemoved, changes[8].Type)
+		chk.Equal("server", changes[8].Section)
+		chk.Equal(1, changes[8].Index)
+		chk.Equal("", changes[8].Key)
+		chk.Equal(9, changes[8].OldPosition.Line)
+		chk.Equal(conf.ChangeRemoved, changes[9].Type)
+		chk.Equal("listen", changes[9].Key)
+		chk.Equal([]string{":8080"}, changes[9].Old)
+	}
+	//
+	chk.Equal(`@@ global @@
+-debug = true
++name = app
+-version = 1.0
++version = 1.1
+@@ [database] #0 @@
+-[database]
+-host = localhost
+@@ [logging] #0 @@
++[logging]
++level = debug
+@@ [server] #0 @@
+ listen = :80
++listen = :8443
+ listen = :443
+@@ [server] #1 @@
+-[server]
+-listen = :8080
+`, changes.String())
+	//
+	chk.Len(conf.Diff(a, a), 0)
+	chk.Equal("", conf.Diff(a, a).String())
+	chk.Len(conf.Diff(nil, nil), 0)
+	changes = conf.Diff(nil, b)
+	if chk.NotEmpty(changes) {
+		chk.Equal(conf.ChangeAdded, changes[0].Type)
+		chk.Equal("name", changes[0].Key)
+	}
+}
+
+func TestDiffSubsections(t *testing.T) {
+	chk := assert.New(t)
+	//
+	loader := conf.Loader{Parser: parser.DefaultParser}
+	loader.Parser.Subsections = parser.SubsectionsQuoted
+	a, err := loader.String(`
+[remote "a"]
+url = one
+
+[remote "b"]
+url = two
+
+[remote]
+url = none
+
+[remote "c"]
+url = three
+`)
+	chk.NoError(err)
+	b, err := loader.String(`
+[remote "b"]
+url = two
+
+[remote "a"]
+url = uno
+
+[remote]
+url = none
+
+[remote "d"]
+url = four
+`)
+	chk.NoError(err)
+	// Sections are matched by subsection rather than position.
+	changes := conf.Diff(a, b)
+	if chk.Len(changes, 5) {
+		chk.Equal(conf.Change{Type: conf.ChangeModified, Section: "remote", Subsection: "a", Index: 1, Key: "url",
+			Old: []string{"one"}, New: []string{"uno"}, OldPosition: changes[0].OldPosition, NewPosition: changes[0].NewPosition},
+			changes[0])
+		chk.Equal(3, changes[0].OldPosition.Line)
+		chk.Equal(6, changes[0].NewPosition.Line)
+		chk.Equal(conf.ChangeRemoved, changes[1].Type)
+		chk.Equal("c", changes[1].Subsection)
+		chk.Equal(3, changes[1].Index)
+	}
+	chk.Equal("@@ [remote \"a\"] #1 @@\n"+
+		"-url = one\n"+
+		"+url = uno\n"+
+		"@@ [remote \"c\"] #3 @@\n"+
+		"-[remote \"c\"]\n"+
+		"-url = three\n"+
+		"@@ [remote \"d\"] #3 @@\n"+
+		"+[remote \"d\"]\n"+
+		"+url = four\n", changes.String())
+	chk.Len(conf.Diff(a, a), 0)
+}
+
+func TestChangeType(t *testing.T) {
+	chk := assert.New(t)
+	//
+	chk.Equal("Added", conf.ChangeAdded.String())
+	chk.Equal("Removed", conf.ChangeRemoved.String())
+	chk.Equal("Modified", conf.ChangeModified.String())
+	chk.Equal("Unknown conf.ChangeType= 0", conf.ChangeType(0).String())
+}
@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// SkipBackups returns true for hidden files and editor backup or swap files such as .hidden, file~, #file#,
+// file.swp, file.swo, file.bak, and file.orig; it is the default Loader.Skip.
+func SkipBackups(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") ||
+		(strings.HasPrefix(base, "#") && strings.HasSuffix(base, "#")) {
+		return true
+	}
+	switch filepath.Ext(base) {
+	case ".swp", ".swo", ".bak", ".orig":
+		return true
+	}
+	return false
+}
+
+// DirSource returns a Source that parses every file in the directory in lexical order and merges them as
+// determined by Loader.Merge.  Symbolic links to files are followed; subdirectories, broken links, and files
+// for which Loader.Skip returns true are ignored.
+func DirSource(dir string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, errors.Go(err)
+		}
+		var names []string
+		for _, info := range infos {
+			name := filepath.Join(dir, info.Name())
+			// ReadDir does not follow symbolic links.
+			if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+				names = append(names, name)
+			}
+		}
+		return l.loadFiles(names)
+	}
+}
+
+// GlobSource returns a Source that parses every file matching the pattern in lexical order and merges them
+// as determined by Loader.Merge; see filepath.Glob for the pattern syntax.  Directories and files for which
+// Loader.Skip returns true are ignored; a pattern with no matches is not an error.
+func GlobSource(pattern string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Go(err)
+		}
+		var names []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				names = append(names, match)
+			}
+		}
+		return l.loadFiles(names)
+	}
+}
+
+// loadFiles parses and merges the files in lexical order after removing those skipped by me.Skip.
+func (me Loader) loadFiles(names []string) (parser.Parsed, error) {
+	skip := me.Skip
+	if skip == nil {
+		skip = SkipBackups
+	}
+	sort.Strings(names)
+	var sources []Source
+	for _, name := range names {
+		if !skip(name) {
+			sources = append(sources, FileSource(name))
+		}
+	}
+	return me.load(sources)
+}
+
+// Dir returns a Conf type by parsing and merging every file in the directory; see DirSource.
+func (me Loader) Dir(dir string) (*Conf, error) {
+	return me.Load(DirSource(dir))
+}
+
+// Glob returns a Conf type by parsing and merging every file matching the pattern; see GlobSource.
+func (me Loader) Glob(pattern string) (*Conf, error) {
+	return me.Load(GlobSource(pattern))
+}
+
+// Dir returns a Conf type by parsing and merging every file in the directory with parser.DefaultParser;
+// see DirSource.
+func Dir(dir string) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.Dir(dir)
+}
+
+// Glob returns a Conf type by parsing and merging every file matching the pattern with parser.DefaultParser;
+// see GlobSource.
+func Glob(pattern string) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.Glob(pattern)
+}
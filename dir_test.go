@@ -0,0 +1,126 @@
+package conf_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestDir(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir, err := ioutil.TempDir("", "gotest")
+	chk.NoError(err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"10-base.conf":        "name = base\nport = 80\n",
+		"20-override.conf":    "name = override\n",
+		"20-override.conf~":   "name = backup\n",
+		".20-override.swp":    "name = swap\n",
+		"30-extra.conf.swp":   "name = swap\n",
+		"30-extra.conf.bak":   "name = bak\n",
+		"#30-extra.conf#":     "name = emacs\n",
+		"99-last.txt":         "last = yes\n",
+		"subdir/ignored.conf": "name = subdir\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		chk.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		chk.NoError(ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	type T struct {
+		Name string `conf:"name"`
+		Port int    `conf:"port"`
+		Last string `conf:"last"`
+	}
+	{
+		c, err := conf.Dir(dir)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "override", Port: 80, Last: "yes"}, t)
+	}
+	{
+		c, err := conf.Glob(filepath.Join(dir, "*.conf"))
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "override", Port: 80}, t)
+		// No matches.
+		c, err = conf.Glob(filepath.Join(dir, "*.missing"))
+		chk.NoError(err)
+		chk.NotNil(c)
+	}
+	{
+		// Custom skip hook.
+		loader := conf.Loader{Parser: parser.DefaultParser}
+		loader.Skip = func(name string) bool {
+			return !strings.HasSuffix(name, "~")
+		}
+		c, err := loader.Dir(dir)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("backup", t.Name)
+		// Combined with other sources.
+		c, err = loader.Load(conf.StringSource("port = 8080\n"), conf.GlobSource(filepath.Join(dir, "*~")))
+		chk.NoError(err)
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "backup", Port: 8080}, t)
+	}
+	{
+		// Symbolic links to fragments are followed by both Dir and Glob; broken links are ignored.
+		available := filepath.Join(dir, "subdir", "available.conf")
+		chk.NoError(ioutil.WriteFile(available, []byte("port = 8080\n"), 0644))
+		link, broken := filepath.Join(dir, "95-linked.conf"), filepath.Join(dir, "96-broken.conf")
+		if err := os.Symlink(available, link); err != nil {
+			t.Skip("symbolic links are not supported; " + err.Error())
+		}
+		chk.NoError(os.Symlink(filepath.Join(dir, "missing.conf"), broken))
+		c, err := conf.Dir(dir)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "override", Port: 8080, Last: "yes"}, t)
+		c, err = conf.Glob(filepath.Join(dir, "*.conf"))
+		chk.NoError(err)
+		t = T{}
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "override", Port: 8080}, t)
+		chk.NoError(os.Remove(link))
+		chk.NoError(os.Remove(broken))
+	}
+	{
+		// Errors are attributed to the fragment.
+		bad := filepath.Join(dir, "50-bad.conf")
+		chk.NoError(ioutil.WriteFile(bad, []byte("name = 'unterminated\n"), 0644))
+		_, err := conf.Dir(dir)
+		var syntaxErr *parser.SyntaxError
+		chk.True(errors.As(err, &syntaxErr))
+		chk.Equal(bad, syntaxErr.File)
+		chk.NoError(os.Remove(bad))
+		//
+		_, err = conf.Dir(filepath.Join(dir, "missing"))
+		chk.Error(err)
+		_, err = conf.Glob("[")
+		chk.Error(err)
+	}
+}
+
+func TestSkipBackups(t *testing.T) {
+	chk := assert.New(t)
+	for _, name := range []string{"a.conf~", "/etc/.a.conf", "#a.conf#", "a.swp", "a.swo", "a.bak", "a.orig"} {
+		chk.True(conf.SkipBackups(name), name)
+	}
+	for _, name := range []string{"a.conf", "/etc/a", "a#", "swp"} {
+		chk.False(conf.SkipBackups(name), name)
+	}
+}
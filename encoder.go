@@ -0,0 +1,351 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// Encoder writes structs as configuration text that parser.DefaultParser parses back into the same struct.
+type Encoder struct {
+	// Tag is the struct tag that names keys and sections; when empty the field names are used.
+	Tag string
+	// Subsections is the rule of the parser that reads the configuration text.  When it is not
+	// parser.SubsectionsNone a field with the name option is written as a quoted subsection in the section
+	// header as in [remote "origin"]; otherwise a field with the name option that is not empty returns an error.
+	Subsections parser.SubsectionRule
+
+	w io.Writer
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Marshal returns the configuration text for v, which should be a struct or pointer to struct; keys and
+// sections are named by field names.
+func Marshal(v interface{}) ([]byte, error) {
+	return MarshalByTag("", v)
+}
+
+// MarshalByTag returns the configuration text for v, which should be a struct or pointer to struct; keys and
+// sections are named by the given struct tag.
+func MarshalByTag(tag string, v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.Tag = tag
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes the configuration text for v, which should be a struct or pointer to struct.
+//
+// Scalar fields and scalar slices are written first as keys in the global section; a slice is written as a
+// repeated key.  Struct fields are then written as sections and slices of structs as repeated sections.  A map
+// of scalars or scalar slices is written as a section with a key for each map key; a map of structs is written
+// as a section named by the field name and map key for each map key.  Map keys are written in sorted order and
+// fields with the name option within a map of structs are not written; the map key names the subsection.
+// Maps with other types return an error.  Nil pointers, nil maps, unexported fields, and fields of other types
+// are skipped.  Values are quoted when required to preserve them.
+func (me *Encoder) Encode(v interface{}) error {
+	if me == nil {
+		return errors.NilReceiver()
+	}
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() || rv.Kind() != reflect.Struct {
+		return errors.Errorf("Encode expects a struct or pointer to struct; got %T", v)
+	}
+	//
+	buf := &bytes.Buffer{}
+	if err := me.keys(buf, rv); err != nil {
+		return err
+	}
+	for _, field := range me.fields(rv) {
+		value := indirect(field.value)
+		if !value.IsValid() {
+			continue
+		} else if value.Kind() == reflect.Struct {
+			if err := me.subsection(buf, field.name, value); err != nil {
+				return err
+			}
+		} else if value.Kind() == reflect.Slice && isStruct(value.Type().Elem()) {
+			for k, size := 0, value.Len(); k < size; k++ {
+				if elem := indirect(value.Index(k)); elem.IsValid() {
+					if err := me.subsection(buf, field.name, elem); err != nil {
+						return err
+					}
+				}
+			}
+		} else if value.Kind() == reflect.Map && !value.IsNil() {
+			if err := me.mapSections(buf, field.name, value); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := me.w.Write(buf.Bytes())
+	return errors.Go(err)
+}
+
+// encodedField is a field to encode along with its key or section name.
+type encodedField struct {
+	name  string
+	value reflect.Value
+}
+
+// fields returns the exported fields of the struct value that are named by the Encoder's tag; fields with the
+// name option are returned by nameField.
+func (me *Encoder) fields(value reflect.Value) []encodedField {
+	var rv []encodedField
+	T := value.Type()
+	for k, size := 0, T.NumField(); k < size; k++ {
+		field := T.Field(k)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if me.Tag != "" {
+			tagValue, ok := field.Tag.Lookup(me.Tag)
+			if !ok || tagOption(tagValue, "name") {
+				continue
+			}
+			name = tagName(tagValue)
+		}
+		rv = append(rv, encodedField{name: name, value: value.Field(k)})
+	}
+	return rv
+}
+
+// nameField returns the value of the first field of the struct value with the name option; the zero
+// reflect.Value is returned if there is none.
+func (me *Encoder) nameField(value reflect.Value) reflect.Value {
+	if me.Tag == "" {
+		return reflect.Value{}
+	}
+	T := value.Type()
+	for k, size := 0, T.NumField(); k < size; k++ {
+		field := T.Field(k)
+		if tagValue, ok := field.Tag.Lookup(me.Tag); ok && field.PkgPath == "" && tagOption(tagValue, "name") {
+			return indirect(value.Field(k))
+		}
+	}
+	return reflect.Value{}
+}
+
+// subsection writes the struct value as a section whose header names the subsection in the field with the
+// name option.
+func (me *Encoder) subsection(buf *bytes.Buffer, name string, value reflect.Value) error {
+	str := ""
+	if field := me.nameField(value); field.IsValid() && isScalar(field.Type()) {
+		str = formatScalar(field)
+	}
+	if str == "" {
+		return me.section(buf, name, value)
+	} else if me.Subsections == parser.SubsectionsNone {
+		return errors.Errorf("Encoding section %v; subsection %q requires Encoder.Subsections", name, str)
+	}
+	quoted := ""
+	for _, q := range parser.DefaultParser.Quote {
+		if !strings.ContainsRune(str, q) && !strings.ContainsAny(str, "\r\n") {
+			quoted = string(q) + str + string(q)
+			break
+		}
+	}
+	if quoted == "" {
+		return errors.Errorf("Encoding section %v; subsection can not be quoted= %q", name, str)
+	}
+	if err := me.header(buf, name, quoted); err != nil {
+		return err
+	}
+	return me.keys(buf, value)
+}
+
+// section writes the section header and keys for the struct value.
+func (me *Encoder) section(buf *bytes.Buffer, name string, value reflect.Value) error {
+	if err := me.header(buf, name, ""); err != nil {
+		return err
+	}
+	return me.keys(buf, value)
+}
+
+// header writes the header of the section with the given name; quoted is the quoted subsection or empty.
+func (me *Encoder) header(buf *bytes.Buffer, name string, quoted string) error {
+	if !validName(name) || strings.ContainsAny(name, "[]") {
+		return errors.Errorf("Invalid section name= %q", name)
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	if quoted != "" {
+		fmt.Fprintf(buf, "[%v %v]\n", name, quoted)
+	} else {
+		fmt.Fprintf(buf, "[%v]\n", name)
+	}
+	return nil
+}
+
+// mapSections writes the map value of the field with the given name.  A map of scalars or scalar slices is
+// written as one section; a map of structs is written as a section for each map key.
+func (me *Encoder) mapSections(buf *bytes.Buffer, name string, value reflect.Value) error {
+	T := value.Type()
+	if T.Key().Kind() != reflect.String {
+		return errors.Errorf("Unsupported map field %v; %v", name, T)
+	}
+	keys := value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	if isStruct(T.Elem()) {
+		for _, key := range keys {
+			if !validName(key.String()) {
+				return errors.Errorf("Invalid map key for %v= %q", name, key.String())
+			} else if elem := indirect(value.MapIndex(key)); elem.IsValid() {
+				if err := me.section(buf, name+" "+key.String(), elem); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	} else if !isScalar(T.Elem()) && (T.Elem().Kind() != reflect.Slice || !isScalar(T.Elem().Elem())) {
+		return errors.Errorf("Unsupported map field %v; %v", name, T)
+	}
+	if err := me.header(buf, name, ""); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := me.key(buf, key.String(), indirect(value.MapIndex(key))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keys writes the scalar and scalar slice fields of the struct value as key=value pairs.
+func (me *Encoder) keys(buf *bytes.Buffer, value reflect.Value) error {
+	for _, field := range me.fields(value) {
+		fieldValue := indirect(field.value)
+		if !fieldValue.IsValid() {
+			continue
+		} else if !isScalar(fieldValue.Type()) &&
+			(fieldValue.Kind() != reflect.Slice || !isScalar(fieldValue.Type().Elem())) {
+			continue
+		}
+		if err := me.key(buf, field.name, fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key writes the scalar or scalar slice value as key=value pairs; a slice is written as a repeated key.
+func (me *Encoder) key(buf *bytes.Buffer, name string, value reflect.Value) error {
+	if !value.IsValid() {
+		return nil
+	}
+	var values []reflect.Value
+	if value.Kind() == reflect.Slice {
+		for k, size := 0, value.Len(); k < size; k++ {
+			if elem := indirect(value.Index(k)); elem.IsValid() {
+				values = append(values, elem)
+			}
+		}
+	} else {
+		values = append(values, value)
+	}
+	if !validName(name) || strings.Contains(name, "=") {
+		return errors.Errorf("Invalid key name= %q", name)
+	}
+	for _, v := range values {
+		str, err := quote(formatScalar(v))
+		if err != nil {
+			return errors.Errorf("Encoding key %v; %v", name, err)
+		}
+		fmt.Fprintf(buf, "%v = %v\n", name, str)
+	}
+	return nil
+}
+
+// indirect dereferences pointers and interfaces; it returns the zero reflect.Value for nil.
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// isScalar returns true if T, after dereferencing pointers, is a bool, number, or string.
+func isScalar(T reflect.Type) bool {
+	for T.Kind() == reflect.Ptr {
+		T = T.Elem()
+	}
+	switch T.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isStruct returns true if T, after dereferencing pointers, is a struct.
+func isStruct(T reflect.Type) bool {
+	for T.Kind() == reflect.Ptr {
+		T = T.Elem()
+	}
+	return T.Kind() == reflect.Struct
+}
+
+// formatScalar returns the string form of a scalar value.
+func formatScalar(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	}
+	return value.String()
+}
+
+// quote returns str quoted if parser.DefaultParser would not otherwise preserve it; an error is returned if
+// str contains a line break and every quote rune.
+func quote(str string) (string, error) {
+	first, _ := utf8.DecodeRuneInString(str)
+	last, _ := utf8.DecodeLastRuneInString(str)
+	if str != "" && !unicode.IsSpace(first) && !unicode.IsSpace(last) && !strings.ContainsAny(str, "\r\n") &&
+		!parser.DefaultParser.IsQuote(first) {
+		return str, nil
+	}
+	for _, q := range parser.DefaultParser.Quote {
+		if !strings.ContainsRune(str, q) {
+			return string(q) + str + string(q), nil
+		}
+	}
+	return "", errors.Errorf("value can not be quoted= %q", str)
+}
+
+// validName returns true if name begins and ends with a letter or digit and does not contain a line break.
+func validName(name string) bool {
+	first, _ := utf8.DecodeRuneInString(name)
+	last, _ := utf8.DecodeLastRuneInString(name)
+	return name != "" && !notAlphaNum(first) && !notAlphaNum(last) && !strings.ContainsAny(name, "\r\n")
+}
@@ -0,0 +1,236 @@
+package conf_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestMarshal(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type Server struct {
+		Listen  string  `conf:"listen"`
+		Weight  float64 `conf:"weight"`
+		Enabled bool    `conf:"enabled"`
+	}
+	type T struct {
+		Database struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port,required"`
+		} `conf:"database"`
+		Servers []*Server `conf:"server"`
+		//
+		Name       string   `conf:"name"`
+		Empty      string   `conf:"empty"`
+		Spaces     string   `conf:"spaces"`
+		Multi      string   `conf:"multi line"`
+		Quoted     string   `conf:"quoted"`
+		Hosts      []string `conf:"hosts"`
+		Unsigned   uint8    `conf:"unsigned"`
+		Skipped    string
+		unexported string
+	}
+	var v T
+	v.Database.Host, v.Database.Port = "localhost", 5432
+	v.Servers = []*Server{{Listen: ":80", Weight: 0.5, Enabled: true}, nil, {Listen: ":81"}}
+	v.Name, v.Spaces, v.Multi, v.Quoted = "example", "  padded ", "one\ntwo", `"it's"`
+	v.Hosts, v.Unsigned, v.Skipped, v.unexported = []string{"a", "b"}, 200, "skipped", "unexported"
+	//
+	b, err := conf.MarshalByTag("conf", &v)
+	chk.NoError(err)
+	chk.Equal("name = example\n"+
+		"empty = ''\n"+
+		"spaces = '  padded '\n"+
+		"multi line = 'one\ntwo'\n"+
+		"quoted = `\"it's\"`\n"+
+		"hosts = a\n"+
+		"hosts = b\n"+
+		"unsigned = 200\n"+
+		"\n"+
+		"[database]\n"+
+		"host = localhost\n"+
+		"port = 5432\n"+
+		"\n"+
+		"[server]\n"+
+		"listen = :80\n"+
+		"weight = 0.5\n"+
+		"enabled = true\n"+
+		"\n"+
+		"[server]\n"+
+		"listen = :81\n"+
+		"weight = 0\n"+
+		"enabled = false\n", string(b))
+	//
+	c, err := conf.String(string(b))
+	chk.NoError(err)
+	var u T
+	err = c.FillByTag("conf", &u)
+	chk.NoError(err)
+	v.Servers = []*Server{v.Servers[0], v.Servers[2]}
+	v.Skipped, v.unexported = "", ""
+	chk.Equal(v, u)
+	{
+		// Field names and the Encoder.
+		type U struct {
+			Name    string
+			Section struct {
+				Key int
+			}
+		}
+		var u U
+		u.Name, u.Section.Key = "example", 42
+		buf := &bytes.Buffer{}
+		err = conf.NewEncoder(buf).Encode(u)
+		chk.NoError(err)
+		chk.Equal("Name = example\n\n[Section]\nKey = 42\n", buf.String())
+		b, err = conf.Marshal(u)
+		chk.NoError(err)
+		chk.Equal(buf.String(), string(b))
+	}
+	{
+		// Errors.
+		_, err = conf.Marshal(42)
+		chk.Error(err)
+		_, err = conf.Marshal(struct {
+			V string `conf:"=key"`
+		}{})
+		chk.NoError(err)
+		_, err = conf.MarshalByTag("conf", struct {
+			V string `conf:"=key"`
+		}{})
+		chk.Error(err)
+		_, err = conf.MarshalByTag("conf", struct {
+			V struct{} `conf:"[section]"`
+		}{})
+		chk.Error(err)
+		_, err = conf.Marshal(struct{ V string }{"'\"`\n"})
+		chk.Error(err)
+		var enc *conf.Encoder
+		chk.Error(enc.Encode(struct{}{}))
+	}
+}
+
+func TestMarshalMaps(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type Backend struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+	type T struct {
+		Name     string              `conf:"name"`
+		Headers  map[string]string   `conf:"headers"`
+		Lists    map[string][]string `conf:"lists"`
+		Backends map[string]*Backend `conf:"backend"`
+		Nil      map[string]string   `conf:"nil"`
+	}
+	v := T{
+		Name:     "example",
+		Headers:  map[string]string{"X-Frame-Options": "DENY", "Cache-Control": "no cache "},
+		Lists:    map[string][]string{"hosts": {"a", "b"}},
+		Backends: map[string]*Backend{"web": {Host: "10.0.0.1", Port: 80}, "api": {Host: "10.0.0.2", Port: 8080}},
+	}
+	b, err := conf.MarshalByTag("conf", &v)
+	chk.NoError(err)
+	chk.Equal("name = example\n"+
+		"\n"+
+		"[headers]\n"+
+		"Cache-Control = 'no cache '\n"+
+		"X-Frame-Options = DENY\n"+
+		"\n"+
+		"[lists]\n"+
+		"hosts = a\n"+
+		"hosts = b\n"+
+		"\n"+
+		"[backend api]\n"+
+		"host = 10.0.0.2\n"+
+		"port = 8080\n"+
+		"\n"+
+		"[backend web]\n"+
+		"host = 10.0.0.1\n"+
+		"port = 80\n", string(b))
+	//
+	c, err := conf.String(string(b))
+	chk.NoError(err)
+	var u T
+	err = c.FillByTag("conf", &u)
+	chk.NoError(err)
+	chk.Equal(v, u)
+	{
+		// Errors.
+		_, err = conf.Marshal(struct{ V map[int]string }{map[int]string{1: "one"}})
+		chk.Error(err)
+		_, err = conf.Marshal(struct{ V map[string]map[string]string }{map[string]map[string]string{"a": nil}})
+		chk.Error(err)
+		_, err = conf.Marshal(struct{ V map[string]Backend }{map[string]Backend{" a": {}}})
+		chk.Error(err)
+		_, err = conf.Marshal(struct{ V map[string]string }{map[string]string{"a=b": "c"}})
+		chk.Error(err)
+	}
+}
+
+func TestMarshalSubsections(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type Remote struct {
+		Name string `conf:",name"`
+		URL  string `conf:"url"`
+	}
+	type T struct {
+		Remotes []Remote          `conf:"remote"`
+		Branch  Remote            `conf:"branch"`
+		ByName  map[string]Remote `conf:"mirror"`
+	}
+	v := T{
+		Remotes: []Remote{{Name: "origin", URL: "a"}, {URL: "b"}, {Name: "it's \"odd\"", URL: "c"}},
+		Branch:  Remote{Name: "main", URL: "d"},
+		ByName:  map[string]Remote{"backup": {Name: "backup", URL: "e"}},
+	}
+	{
+		// Subsections require a rule.
+		_, err := conf.MarshalByTag("conf", &v)
+		chk.Error(err)
+		b, err := conf.MarshalByTag("conf", T{ByName: v.ByName})
+		chk.NoError(err)
+		chk.Equal("[branch]\nurl = ''\n\n[mirror backup]\nurl = e\n", string(b))
+	}
+	for _, rule := range []parser.SubsectionRule{parser.SubsectionsQuoted, parser.SubsectionsSpace} {
+		buf := &bytes.Buffer{}
+		enc := conf.NewEncoder(buf)
+		enc.Tag, enc.Subsections = "conf", rule
+		chk.NoError(enc.Encode(&v))
+		chk.Equal("[remote 'origin']\n"+
+			"url = a\n"+
+			"\n"+
+			"[remote]\n"+
+			"url = b\n"+
+			"\n"+
+			"[remote `it's \"odd\"`]\n"+
+			"url = c\n"+
+			"\n"+
+			"[branch 'main']\n"+
+			"url = d\n"+
+			"\n"+
+			"[mirror backup]\n"+
+			"url = e\n", buf.String())
+		//
+		loader := conf.Loader{Parser: parser.DefaultParser}
+		loader.Parser.Subsections = rule
+		c, err := loader.String(buf.String())
+		chk.NoError(err)
+		var u T
+		chk.NoError(c.FillByTag("conf", &u))
+		chk.Equal(v, u)
+	}
+	{
+		// Subsections that can not be quoted.
+		enc := conf.NewEncoder(&bytes.Buffer{})
+		enc.Tag, enc.Subsections = "conf", parser.SubsectionsQuoted
+		chk.Error(enc.Encode(T{Branch: Remote{Name: "a\nb"}}))
+	}
+}
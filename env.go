@@ -0,0 +1,133 @@
+package conf
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// envOverlay applies environment variables to parsed configuration before it is filled.
+type envOverlay struct {
+	opts FillOptions
+}
+
+// overlayEnv replaces values in m with the environment variables named by fields.
+func overlayEnv(m map[string][]map[string][]string, fields []set.Field, opts FillOptions) {
+	if opts.EnvTag == "" {
+		opts.EnvTag = "env"
+	}
+	if opts.EnvSeparator == "" {
+		opts.EnvSeparator = ","
+	}
+	overlay := envOverlay{opts: opts}
+	//
+	global := m[""][len(m[""])-1]
+	for _, field := range fields {
+		name, explicit := fieldName(field, opts.Tag), field.Field.Tag.Get(opts.EnvTag)
+		if field.Value.IsScalar || (field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
+			if explicit == "" {
+				explicit = envName(opts.EnvPrefix, name)
+			}
+			overlay.value(global, name, explicit, field.Value.IsSlice)
+		} else if field.Value.IsStruct {
+			// A struct receives the last section with the name.
+			prefix := explicit
+			if prefix == "" {
+				prefix = envName(opts.EnvPrefix, name)
+			}
+			sections := m[name]
+			section := map[string][]string{}
+			if len(sections) > 0 {
+				section = sections[len(sections)-1]
+			}
+			if overlay.section(section, field.Value.Type, prefix, -1) && len(sections) == 0 {
+				m[name] = append(sections, section)
+			}
+		} else if field.Value.IsSlice && field.Value.ElemTypeInfo.IsStruct {
+			// A slice receives every section with the name; environment variables for the index one past
+			// the last section append another section.
+			prefix := explicit
+			if prefix == "" {
+				prefix = envName(opts.EnvPrefix, name)
+			}
+			sections := m[name]
+			for k := 0; ; k++ {
+				if k < len(sections) {
+					overlay.section(sections[k], field.Value.ElemTypeInfo.Type, prefix, k)
+					continue
+				}
+				section := map[string][]string{}
+				if !overlay.section(section, field.Value.ElemTypeInfo.Type, prefix, k) {
+					break
+				}
+				sections = append(sections, section)
+			}
+			m[name] = sections
+		}
+	}
+}
+
+// section replaces values in section with environment variables named by the fields of T; index is the
+// position of a repeated section or -1.  It returns true if any environment variable was set.
+func (me envOverlay) section(section map[string][]string, T reflect.Type, prefix string, index int) bool {
+	suffix := ""
+	if index >= 0 {
+		suffix = strconv.Itoa(index)
+		prefix = envName(prefix, suffix)
+	}
+	rv := false
+	for _, field := range typeFields(T, me.opts.Tag) {
+		if !field.Value.IsScalar && !(field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
+			continue
+		} else if isSubsection(field, me.opts.Tag) {
+			continue
+		}
+		name := envName(prefix, fieldName(field, me.opts.Tag))
+		if explicit := field.Field.Tag.Get(me.opts.EnvTag); explicit != "" {
+			// An explicit name within a repeated section is suffixed with the section index.
+			name = explicit
+			if suffix != "" {
+				name = explicit + "_" + suffix
+			}
+		}
+		rv = me.value(section, fieldName(field, me.opts.Tag), name, field.Value.IsSlice) || rv
+	}
+	return rv
+}
+
+// value replaces section[key] with the environment variable name if it is set; it returns true if the
+// variable was set.
+func (me envOverlay) value(section map[string][]string, key string, name string, slice bool) bool {
+	str, ok := os.LookupEnv(name)
+	if !ok {
+		return false
+	}
+	if slice {
+		section[key] = strings.Split(str, me.opts.EnvSeparator)
+	} else {
+		section[key] = []string{str}
+	}
+	return true
+}
+
+// envName joins the parts into an environment variable name; parts are upper cased and runs of characters
+// other than letters and digits become underscores.
+func envName(parts ...string) string {
+	var names []string
+	for _, part := range parts {
+		part = strings.Trim(strings.Join(strings.FieldsFunc(strings.ToUpper(part), notAlphaNum), "_"), "_")
+		if part != "" {
+			names = append(names, part)
+		}
+	}
+	return strings.Join(names, "_")
+}
+
+// notAlphaNum returns true if r is not a letter or digit.
+func notAlphaNum(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
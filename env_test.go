@@ -0,0 +1,128 @@
+package conf_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+// setenv sets the environment variables and returns a function to unset them.
+func setenv(vars map[string]string) func() {
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestConf_FillWithEnv(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+name = from file
+hosts = a
+hosts = b
+port = 80
+
+[database]
+host = localhost
+user = root
+
+[server]
+listen = :80
+
+[server]
+listen = :81
+`
+	type T struct {
+		Name  string   `conf:"name"`
+		Hosts []string `conf:"hosts"`
+		Port  int      `conf:"port" env:"HTTP_PORT"`
+		//
+		Database struct {
+			Host     string `conf:"host"`
+			User     string `conf:"user"`
+			Password string `conf:"password" env:"DB_PASSWORD"`
+		} `conf:"database"`
+		//
+		Servers []struct {
+			Listen string `conf:"listen"`
+			TLS    bool   `conf:"tls" env:"SERVER_TLS"`
+		} `conf:"server"`
+	}
+	c, err := conf.String(s)
+	chk.NoError(err)
+	{
+		// Env not enabled.
+		defer setenv(map[string]string{"APP_NAME": "from env"})()
+		var t T
+		err = c.FillWith(&t, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		chk.Equal("from file", t.Name)
+	}
+	{
+		// Nothing set.
+		var t T
+		err = c.FillWith(&t, conf.FillOptions{Tag: "conf", Env: true, EnvPrefix: "APP_TEST"})
+		chk.NoError(err)
+		chk.Equal("from file", t.Name)
+		chk.Equal([]string{"a", "b"}, t.Hosts)
+		chk.Equal(80, t.Port)
+		chk.Equal("localhost", t.Database.Host)
+		chk.Equal(2, len(t.Servers))
+	}
+	{
+		defer setenv(map[string]string{
+			"APP_NAME":            "from env",
+			"APP_HOSTS":           "x,y,z",
+			"HTTP_PORT":           "8080",
+			"APP_DATABASE_HOST":   "db.example.com",
+			"DB_PASSWORD":         "secret",
+			"APP_SERVER_1_LISTEN": ":443",
+			"SERVER_TLS_1":        "true",
+			"APP_SERVER_2_LISTEN": ":8443",
+		})()
+		var t T
+		err = c.FillWith(&t, conf.FillOptions{Tag: "conf", Env: true, EnvPrefix: "app"})
+		chk.NoError(err)
+		chk.Equal("from env", t.Name)
+		chk.Equal([]string{"x", "y", "z"}, t.Hosts)
+		chk.Equal(8080, t.Port)
+		chk.Equal("db.example.com", t.Database.Host)
+		chk.Equal("root", t.Database.User)
+		chk.Equal("secret", t.Database.Password)
+		chk.Equal(3, len(t.Servers))
+		chk.Equal(":80", t.Servers[0].Listen)
+		chk.Equal(false, t.Servers[0].TLS)
+		chk.Equal(":443", t.Servers[1].Listen)
+		chk.Equal(true, t.Servers[1].TLS)
+		chk.Equal(":8443", t.Servers[2].Listen)
+		// The parsed configuration is unchanged.
+		var u T
+		err = c.FillByTag("conf", &u)
+		chk.NoError(err)
+		chk.Equal("from file", u.Name)
+		chk.Equal(2, len(u.Servers))
+	}
+	{
+		// Separator and field names.
+		type U struct {
+			Hosts   []string
+			Section struct {
+				KeyName string
+			}
+		}
+		defer setenv(map[string]string{"HOSTS": "x;y", "SECTION_KEYNAME": "value"})()
+		var u U
+		err = c.FillWith(&u, conf.FillOptions{Env: true, EnvSeparator: ";"})
+		chk.NoError(err)
+		chk.Equal([]string{"x", "y"}, u.Hosts)
+		chk.Equal("value", u.Section.KeyName)
+	}
+}
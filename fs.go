@@ -0,0 +1,35 @@
+package conf
+
+import (
+	"io/fs"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+// FSSource returns a Source that parses the named file within fsys.
+func FSSource(fsys fs.FS, name string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		return l.Parser.ParseFS(fsys, name)
+	}
+}
+
+// FS returns a Conf type by reading and parsing the named file within fsys, such as an embed.FS; include
+// directives are resolved within fsys.
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func (me Loader) FS(fsys fs.FS, name string) (*Conf, error) {
+	parsed, err := me.Parser.ParseFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	//
+	return &Conf{parsed}, nil
+}
+
+// FS returns a Conf type by reading and parsing the named file within fsys, such as an embed.FS, with
+// parser.DefaultParser.
+//
+// Syntax errors are returned as *parser.SyntaxError and can be inspected with errors.As.
+func FS(fsys fs.FS, name string) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.FS(fsys, name)
+}
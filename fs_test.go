@@ -0,0 +1,51 @@
+package conf_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestFS(t *testing.T) {
+	chk := assert.New(t)
+	//
+	fsys := fstest.MapFS{
+		"defaults.conf": {Data: []byte("name = default\nport = 80\ninclude = extra.conf\n")},
+		"extra.conf":    {Data: []byte("extra = yes\n")},
+	}
+	type T struct {
+		Name    string `conf:"name"`
+		Port    int    `conf:"port"`
+		Extra   string `conf:"extra"`
+		Include string `conf:"include"`
+	}
+	{
+		c, err := conf.FS(fsys, "defaults.conf")
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "default", Port: 80, Include: "extra.conf"}, t)
+	}
+	{
+		loader := conf.Loader{Parser: parser.DefaultParser}
+		loader.Parser.Include = "include"
+		c, err := loader.FS(fsys, "defaults.conf")
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "default", Port: 80, Extra: "yes"}, t)
+		//
+		c, err = loader.Load(conf.FSSource(fsys, "defaults.conf"), conf.StringSource("port = 8080\n"))
+		chk.NoError(err)
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(T{Name: "default", Port: 8080, Extra: "yes"}, t)
+	}
+	{
+		_, err := conf.FS(fsys, "missing.conf")
+		chk.Error(err)
+	}
+}
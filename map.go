@@ -0,0 +1,133 @@
+package conf
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nofeaturesonlybugs/errors"
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// isMap returns true if field is a map with string keys that can be filled from sections.
+func isMap(field set.Field) bool {
+	return field.Value.IsMap && field.Value.Type.Key().Kind() == reflect.String
+}
+
+// isStructMap returns true if field is a map of structs filled from sections named with a common prefix.
+func isStructMap(field set.Field) bool {
+	return isMap(field) && field.Value.ElemTypeInfo.IsStruct
+}
+
+// subsection returns the remainder of name when it begins with prefix followed by whitespace; the remainder
+// is the key of a section in a map of structs.
+func subsection(name string, prefix string) (string, bool) {
+	if len(name) <= len(prefix) || !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	rest := name[len(prefix):]
+	if trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace); trimmed != rest && trimmed != "" {
+		return strings.TrimRightFunc(trimmed, unicode.IsSpace), true
+	}
+	return "", false
+}
+
+// mapSection is a section filled into a map of structs.
+type mapSection struct {
+	// key is the map key.
+	key string
+	// name and index locate the section in the map returned from Conf.sections; index is -1 when the
+	// last section with the name is used.
+	name  string
+	index int
+	// section is the section's keys and values.
+	section map[string][]string
+}
+
+// mapSections returns the sections filled into the map of structs named prefix.  These are the sections named
+// prefix followed by whitespace and the sections named prefix that name a subsection; when keys repeat the
+// last section is used.  The result is sorted by name and index.
+func mapSections(m map[string][]map[string][]string, prefix string) []mapSection {
+	var names []string
+	for name := range m {
+		if _, ok := subsection(name, prefix); ok && len(m[name]) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var all []mapSection
+	for _, name := range names {
+		key, _ := subsection(name, prefix)
+		all = append(all, mapSection{key: key, name: name, index: -1, section: m[name][len(m[name])-1]})
+	}
+	for k, section := range m[prefix] {
+		if values := section[subsectionKey]; len(values) > 0 {
+			all = append(all, mapSection{key: values[0], name: prefix, index: k, section: section})
+		}
+	}
+	//
+	last := map[string]int{}
+	for k, entry := range all {
+		last[entry.key] = k
+	}
+	var rv []mapSection
+	for k, entry := range all {
+		if last[entry.key] == k {
+			rv = append(rv, entry)
+		}
+	}
+	return rv
+}
+
+// fillMap fills the map field named name from m.  A map of scalars or scalar slices receives the keys of the
+// last section with the name; a map of structs receives a struct for each of mapSections.
+func fillMap(m map[string][]map[string][]string, field set.Field, name string, tag string) error {
+	mapValue, found := reflect.MakeMap(field.Value.Type), false
+	if elem := field.Value.ElemTypeInfo; elem.IsStruct {
+		for _, entry := range mapSections(m, name) {
+			found = true
+			if _, ok := entry.section[subsectionKey]; !ok {
+				entry.section[subsectionKey] = []string{entry.key}
+			}
+			value := reflect.New(field.Value.ElemType)
+			target, getter := set.V(value), optionGetter(set.MapGetter(entry.section))
+			var err error
+			if tag == "" {
+				err = target.Fill(getter)
+			} else {
+				err = target.FillByTag(tag, getter)
+			}
+			if err != nil {
+				return err
+			}
+			mapValue.SetMapIndex(reflect.ValueOf(entry.key).Convert(field.Value.Type.Key()), value.Elem())
+		}
+	} else if elem.IsScalar || (elem.IsSlice && set.TypeCache.StatType(elem.ElemType).IsScalar) {
+		sections := m[name]
+		if found = len(sections) > 0; !found {
+			return field.Value.Zero()
+		}
+		for key, values := range sections[len(sections)-1] {
+			if key == subsectionKey {
+				continue
+			}
+			value := reflect.New(field.Value.ElemType)
+			var arg interface{} = values
+			if elem.IsScalar && len(values) > 0 {
+				arg = values[len(values)-1]
+			}
+			if err := set.V(value).To(arg); err != nil {
+				return err
+			}
+			mapValue.SetMapIndex(reflect.ValueOf(key).Convert(field.Value.Type.Key()), value.Elem())
+		}
+	} else {
+		return errors.Errorf("Unsupported map field %v; %v", name, field.Value.Type)
+	}
+	if !found {
+		return field.Value.Zero()
+	}
+	field.Value.WriteValue.Set(mapValue)
+	return nil
+}
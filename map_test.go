@@ -0,0 +1,126 @@
+package conf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+func TestConf_FillMaps(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+name = app
+
+[headers]
+X-Frame-Options = DENY
+Cache-Control = no-cache
+
+[limits]
+requests = 100
+burst = 10
+
+[allow]
+GET = /
+GET = /health
+POST = /api
+
+[backend web]
+host = 10.0.0.1
+port = 80
+
+[backend   api]
+host = 10.0.0.2
+
+[backends]
+host = ignored
+`
+	type Backend struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port" default:"8080"`
+	}
+	type T struct {
+		Name     string              `conf:"name"`
+		Headers  map[string]string   `conf:"headers"`
+		Limits   map[string]int      `conf:"limits"`
+		Allow    map[string][]string `conf:"allow"`
+		Missing  map[string]string   `conf:"missing"`
+		Backends map[string]Backend  `conf:"backend"`
+		Pointers map[string]*Backend `conf:"backend"`
+	}
+	c, err := conf.String(s)
+	chk.NoError(err)
+	{
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("app", t.Name)
+		chk.Equal(map[string]string{"X-Frame-Options": "DENY", "Cache-Control": "no-cache"}, t.Headers)
+		chk.Equal(map[string]int{"requests": 100, "burst": 10}, t.Limits)
+		chk.Equal(map[string][]string{"GET": {"/", "/health"}, "POST": {"/api"}}, t.Allow)
+		chk.Nil(t.Missing)
+		chk.Equal(map[string]Backend{
+			"web": {Host: "10.0.0.1", Port: 80},
+			"api": {Host: "10.0.0.2", Port: 8080},
+		}, t.Backends)
+		if chk.Len(t.Pointers, 2) && chk.NotNil(t.Pointers["api"]) {
+			chk.Equal(Backend{Host: "10.0.0.2", Port: 8080}, *t.Pointers["api"])
+		}
+	}
+	{
+		// Maps consume every key in their sections.
+		var t T
+		unused, err := c.Unused(&t, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		if chk.Len(unused, 1) {
+			chk.Equal("backends", unused[0].Section)
+			chk.Equal("", unused[0].Key)
+		}
+	}
+	{
+		// Invalid values are errors.
+		var t struct {
+			Headers map[string]int `conf:"headers"`
+		}
+		chk.Error(c.FillByTag("conf", &t))
+	}
+	{
+		// Maps with unsupported elements are errors.
+		var t struct {
+			Headers map[string]map[string]string `conf:"headers"`
+		}
+		chk.Error(c.FillByTag("conf", &t))
+	}
+	{
+		// Required maps and keys of sections within maps.
+		var t struct {
+			Backends map[string]struct {
+				Host string `conf:"host"`
+				Port int    `conf:"port,required"`
+			} `conf:"backend"`
+			Missing map[string]string `conf:"missing,required"`
+		}
+		err = c.FillByTag("conf", &t)
+		var required *conf.RequiredError
+		if chk.True(errors.As(err, &required)) {
+			chk.Equal([]conf.Missing{
+				{Section: "backend   api", Index: -1, Key: "port"},
+				{Section: "missing", Index: -1},
+			}, required.Missing)
+		}
+	}
+	{
+		// Fill by field name.
+		var t struct {
+			Headers map[string]string
+			Backend map[string]Backend
+		}
+		c, err = conf.String("[Headers]\na = b\n\n[Backend web]\nHost = localhost\n")
+		chk.NoError(err)
+		chk.NoError(c.Fill(&t))
+		chk.Equal(map[string]string{"a": "b"}, t.Headers)
+		chk.Equal(map[string]Backend{"web": {Host: "localhost", Port: 8080}}, t.Backend)
+	}
+}
@@ -0,0 +1,223 @@
+package conf
+
+import (
+	"io"
+	"os"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// Source provides configuration to Loader.Load; use DirSource, FileSource, FSSource, GlobSource, OptionalFileSource,
+// ReaderSource, or StringSource.
+type Source func(l Loader) (parser.Parsed, error)
+
+// FileSource returns a Source that parses the named file.
+func FileSource(name string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		return l.Parser.ParseFile(name)
+	}
+}
+
+// OptionalFileSource returns a Source that parses the named file if it exists.
+func OptionalFileSource(name string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return nil, nil
+		}
+		return l.Parser.ParseFile(name)
+	}
+}
+
+// ReaderSource returns a Source that parses the reader.
+func ReaderSource(r io.Reader) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		return l.Parser.ParseReader(r)
+	}
+}
+
+// StringSource returns a Source that parses the string of configuration data.
+func StringSource(s string) Source {
+	return func(l Loader) (parser.Parsed, error) {
+		return l.Parser.Parse(s)
+	}
+}
+
+// KeyPolicy determines how Loader.Load merges a key that appears in more than one source.
+type KeyPolicy int
+
+// Enums for KeyPolicy.
+const (
+	// KeysReplace replaces the values of the key with those from the later source.
+	KeysReplace KeyPolicy = iota
+	// KeysAppend appends the values from the later source to the values of the key.
+	KeysAppend
+)
+
+// SectionPolicy determines how Loader.Load merges a section that appears in more than one source.
+type SectionPolicy int
+
+// Enums for SectionPolicy.
+const (
+	// SectionsMerge merges the keys of each section from the later source into the section at the same
+	// position among sections with the same name; additional sections are appended.  Sections naming a
+	// subsection merge with the section naming the same subsection and positions only count sections
+	// without a subsection.
+	SectionsMerge SectionPolicy = iota
+	// SectionsAppend appends the sections from the later source to the sections with the same name.
+	SectionsAppend
+	// SectionsReplace replaces every section with the same name with the sections from the later source.
+	SectionsReplace
+)
+
+// MergeOptions configures how Loader.Load merges sources.  The global section is always merged with
+// SectionsMerge.
+type MergeOptions struct {
+	Keys     KeyPolicy
+	Sections SectionPolicy
+}
+
+// Load returns a Conf type by parsing and merging the sources in order; later sources override earlier ones
+// as determined by the Loader's Merge options:
+//	c, err := conf.Load(
+//		conf.FileSource("defaults.conf"),
+//		conf.OptionalFileSource("/etc/app/app.conf"),
+//		conf.OptionalFileSource(filepath.Join(home, ".app.conf")),
+//	)
+func (me Loader) Load(sources ...Source) (*Conf, error) {
+	parsed, err := me.load(sources)
+	if err != nil {
+		return nil, err
+	}
+	return &Conf{parsed}, nil
+}
+
+// load parses and merges the sources in order; when the parser interpolates the merged configuration is
+// interpolated once so references can refer to and be overridden by any source.
+func (me Loader) load(sources []Source) (parser.Parsed, error) {
+	global := parser.Section{}
+	rv := parser.Parsed{"": &parser.SectionBlock{Last: global, Slice: []parser.Section{global}, Positions: []parser.Position{{}}}}
+	interpolate := me.Parser.Interpolate
+	me.Parser.Interpolate = false
+	for _, source := range sources {
+		if source == nil {
+			return nil, errors.NilArgument("source")
+		}
+		parsed, err := source(me)
+		if err != nil {
+			return nil, err
+		}
+		me.Merge.merge(rv, parsed)
+	}
+	if interpolate {
+		if err := rv.Interpolate(); err != nil {
+			return nil, err
+		}
+	}
+	return rv, nil
+}
+
+// Load returns a Conf type by parsing and merging the sources in order with parser.DefaultParser; later sources
+// replace keys from earlier sources and sections with the same name are merged.
+func Load(sources ...Source) (*Conf, error) {
+	return Loader{Parser: parser.DefaultParser}.Load(sources...)
+}
+
+// merge merges src into dst.
+func (me MergeOptions) merge(dst, src parser.Parsed) {
+	for name, block := range src {
+		existing, ok := dst[name]
+		if !ok {
+			dst[name] = &parser.SectionBlock{}
+			me.appendSections(dst[name], block, 0)
+			continue
+		}
+		policy := me.Sections
+		if name == "" {
+			policy = SectionsMerge
+		}
+		switch policy {
+		case SectionsReplace:
+			dst[name] = &parser.SectionBlock{}
+			me.appendSections(dst[name], block, 0)
+		case SectionsAppend:
+			me.appendSections(existing, block, 0)
+		default:
+			// Sections naming a subsection merge with the section naming the same subsection; other sections
+			// merge by position among the sections without a subsection.
+			for k, plain := 0, 0; k < len(block.Slice); k++ {
+				n := -1
+				if subsection := block.Subsection(k); subsection != "" {
+					n = subsectionIndex(existing, subsection)
+				} else {
+					n, plain = plainIndex(existing, plain), plain+1
+				}
+				if n != -1 {
+					me.mergeSection(existing.Slice[n], block.Slice[k])
+				} else {
+					me.appendSection(existing, block, k)
+				}
+			}
+		}
+	}
+}
+
+// appendSections appends copies of src.Slice[from:] to dst.
+func (me MergeOptions) appendSections(dst, src *parser.SectionBlock, from int) {
+	for k := from; k < len(src.Slice); k++ {
+		me.appendSection(dst, src, k)
+	}
+}
+
+// appendSection appends a copy of src.Slice[k] to dst.
+func (me MergeOptions) appendSection(dst, src *parser.SectionBlock, k int) {
+	section := parser.Section{}
+	me.mergeSection(section, src.Slice[k])
+	dst.Slice = append(dst.Slice, section)
+	dst.Positions = append(dst.Positions, sectionPosition(src, k))
+	if subsection := src.Subsection(k); subsection != "" || dst.Subsections != nil {
+		// Subsections remain parallel to Slice when only some sources name subsections.
+		for len(dst.Subsections) < len(dst.Slice)-1 {
+			dst.Subsections = append(dst.Subsections, "")
+		}
+		dst.Subsections = append(dst.Subsections, subsection)
+	}
+	dst.Last = section
+}
+
+// subsectionIndex returns the index of the last section in block naming subsection or -1.
+func subsectionIndex(block *parser.SectionBlock, subsection string) int {
+	for k := len(block.Slice) - 1; k >= 0; k-- {
+		if block.Subsection(k) == subsection {
+			return k
+		}
+	}
+	return -1
+}
+
+// plainIndex returns the index of the nth section in block that does not name a subsection or -1.
+func plainIndex(block *parser.SectionBlock, n int) int {
+	for k := range block.Slice {
+		if block.Subsection(k) == "" {
+			if n == 0 {
+				return k
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+// mergeSection merges the keys of src into dst.
+func (me MergeOptions) mergeSection(dst, src parser.Section) {
+	for key, value := range src {
+		existing, ok := dst[key]
+		if !ok || me.Keys != KeysAppend {
+			existing = &parser.Value{}
+			dst[key] = existing
+		}
+		existing.Slice = append(existing.Slice, value.Slice...)
+		existing.Positions = append(existing.Positions, value.Positions...)
+		existing.Last = value.Last
+	}
+}
@@ -0,0 +1,159 @@
+package conf_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestLoad_interpolate(t *testing.T) {
+	chk := assert.New(t)
+	//
+	loader := conf.Loader{Parser: parser.DefaultParser}
+	loader.Parser.Interpolate = true
+	{
+		// References can refer to keys from earlier sources.
+		c, err := loader.Load(conf.StringSource("base = /opt\n"), conf.StringSource("path = ${base}/bin\n"))
+		chk.NoError(err)
+		path, _ := c.Get("", "path")
+		chk.Equal("/opt/bin", path)
+	}
+	{
+		// Later sources override referenced keys.
+		c, err := loader.Load(
+			conf.StringSource("base = /opt\npath = ${base}/bin\n"),
+			conf.StringSource("base = /usr/local\n"),
+		)
+		chk.NoError(err)
+		path, _ := c.Get("", "path")
+		chk.Equal("/usr/local/bin", path)
+	}
+	{
+		// Unknown references are still errors.
+		_, err := loader.Load(conf.StringSource("path = ${missing}/bin\n"))
+		chk.Error(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir, err := ioutil.TempDir("", "gotest")
+	chk.NoError(err)
+	defer os.RemoveAll(dir)
+	defaults := filepath.Join(dir, "defaults.conf")
+	err = ioutil.WriteFile(defaults, []byte(`name = default
+hosts = a
+hosts = b
+
+[database]
+host = localhost
+port = 5432
+
+[server]
+listen = :80
+
+[server]
+listen = :81
+`), 0644)
+	chk.NoError(err)
+	override := `
+name = override
+hosts = c
+
+[database]
+host = db.example.com
+
+[server]
+listen = :8080
+`
+	type T struct {
+		Name     string   `conf:"name"`
+		Hosts    []string `conf:"hosts"`
+		Database struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		} `conf:"database"`
+		Servers []struct {
+			Listen string `conf:"listen"`
+		} `conf:"server"`
+	}
+	listens := func(t T) []string {
+		var rv []string
+		for _, server := range t.Servers {
+			rv = append(rv, server.Listen)
+		}
+		return rv
+	}
+	{
+		// Default policies.
+		c, err := conf.Load(
+			conf.FileSource(defaults),
+			conf.OptionalFileSource(filepath.Join(dir, "missing.conf")),
+			conf.StringSource(override),
+		)
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("override", t.Name)
+		chk.Equal([]string{"c"}, t.Hosts)
+		chk.Equal("db.example.com", t.Database.Host)
+		chk.Equal(5432, t.Database.Port)
+		chk.Equal([]string{":8080", ":81"}, listens(t))
+		// Positions are kept.
+		unused, err := c.Unused(&struct{}{}, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		for _, u := range unused {
+			if u.Section == "database" {
+				chk.Equal(parser.Position{File: defaults, Line: 5, Column: 1}, u.Position)
+			} else if u.Key == "name" {
+				chk.Equal(parser.Position{Line: 2, Column: 1}, u.Position)
+			}
+		}
+	}
+	{
+		loader := conf.Loader{Parser: parser.DefaultParser}
+		loader.Merge.Keys, loader.Merge.Sections = conf.KeysAppend, conf.SectionsAppend
+		c, err := loader.Load(conf.FileSource(defaults), conf.ReaderSource(strings.NewReader(override)))
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("override", t.Name)
+		chk.Equal([]string{"a", "b", "c"}, t.Hosts)
+		chk.Equal("db.example.com", t.Database.Host)
+		chk.Equal(0, t.Database.Port)
+		chk.Equal([]string{":80", ":81", ":8080"}, listens(t))
+	}
+	{
+		loader := conf.Loader{Parser: parser.DefaultParser}
+		loader.Merge.Sections = conf.SectionsReplace
+		c, err := loader.Load(conf.FileSource(defaults), conf.StringSource(override))
+		chk.NoError(err)
+		var t T
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal([]string{"c"}, t.Hosts)
+		chk.Equal(0, t.Database.Port)
+		chk.Equal([]string{":8080"}, listens(t))
+	}
+	{
+		// Errors.
+		_, err := conf.Load(conf.FileSource(filepath.Join(dir, "missing.conf")))
+		chk.Error(err)
+		_, err = conf.Load(conf.StringSource("name = 'unterminated\n"))
+		var syntaxErr *parser.SyntaxError
+		chk.True(errors.As(err, &syntaxErr))
+		_, err = conf.Load(nil)
+		chk.Error(err)
+		c, err := conf.Load()
+		chk.NoError(err)
+		chk.Equal(1, len(c.Sections("")))
+	}
+}
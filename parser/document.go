@@ -0,0 +1,654 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// nodeType describes a node within a Document.
+type nodeType int
+
+// Enums for node types.
+const (
+	nodeBlank nodeType = iota
+	nodeComment
+	nodeSection
+	nodeKey
+)
+
+// node is a span of the original input; key nodes contain a single key = value pair and section nodes
+// contain a section header.
+type node struct {
+	typ nodeType
+	raw string
+	// The key or section name.
+	name string
+	// The value of a key.
+	value string
+}
+
+// Document is a parsed configuration that preserves comments, blank lines, quotations, and ordering so it can
+// be edited and written back; a Document that is not edited is written back byte-for-byte identical to its input.
+//
+// Each key = value pair, section header, comment, or blank line must begin on its own line.  Include directives
+// and ${} references are kept as written; they are not processed by the Document.
+type Document struct {
+	parser   Parser
+	sections []*DocumentSection
+}
+
+// DocumentSection is a section within a Document.
+type DocumentSection struct {
+	doc *Document
+	// The section header; nil for the global section.
+	header *node
+	// The lines following the header up to the next section header.
+	nodes []*node
+}
+
+// ParseDocument parses a string into a Document.
+func (me Parser) ParseDocument(s string) (*Document, error) {
+	me.Include, me.Interpolate = "", false
+	parsed, err := me.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	//
+	rv := &Document{parser: me}
+	current := &DocumentSection{doc: rv}
+	rv.sections = []*DocumentSection{current}
+	if strings.HasPrefix(s, byteOrderMark) {
+		current.nodes = append(current.nodes, &node{typ: nodeBlank, raw: byteOrderMark})
+		s = s[len(byteOrderMark):]
+	}
+	for len(s) > 0 {
+		n := &node{typ: nodeComment, raw: s[:lineEnd(s, 0)]}
+		rest := strings.TrimLeft(n.raw, " \t")
+		r, _ := utf8.DecodeRuneInString(rest)
+		if rest == "" || r == '\r' || r == '\n' {
+			n.typ = nodeBlank
+		} else if me.IsOpenSection(r) {
+			n.typ = nodeSection
+		} else if isAlphaNum(r) {
+			n.typ, n.raw = nodeKey, s[:me.keyEnd(s)]
+		}
+		if err = rv.read(n); err != nil {
+			return nil, err
+		}
+		if n.typ == nodeSection {
+			current = &DocumentSection{doc: rv, header: n}
+			rv.sections = append(rv.sections, current)
+		} else {
+			current.nodes = append(current.nodes, n)
+		}
+		s = s[len(n.raw):]
+	}
+	// Layouts such as two key = value pairs on one line are not represented by the nodes; the document is
+	// compared against the parsed configuration to detect them.
+	if !reflect.DeepEqual(parsed.Map(), rv.parsed().Map()) {
+		return nil, errors.Errorf("Parsing document; each key, section, and comment must begin on its own line")
+	}
+	return rv, nil
+}
+
+// read sets the name and value of section and key nodes by parsing their raw text.
+func (me *Document) read(n *node) error {
+	if n.typ != nodeSection && n.typ != nodeKey {
+		return nil
+	}
+	raw := n.raw
+	if !strings.HasSuffix(raw, "\n") && !strings.HasSuffix(raw, "\r") {
+		// An unquoted value must be followed by a line terminator.
+		raw = raw + "\n"
+	}
+	parsed, err := me.parser.Parse(raw)
+	if err != nil {
+		return err
+	}
+	for name, block := range parsed {
+		if n.typ == nodeSection && name != "" {
+			n.name = name
+			return nil
+		}
+		for key, value := range block.Last {
+			n.name, n.value = key, value.Last
+			return nil
+		}
+	}
+	return errors.Errorf("Parsing document; expected %v", strings.TrimRight(n.raw, "\r\n"))
+}
+
+// parsed returns the configuration described by the nodes.
+func (me *Document) parsed() Parsed {
+	rv := Parsed{}
+	for _, section := range me.sections {
+		current := Section{}
+		if block, ok := rv[section.Name()]; !ok {
+			rv[section.Name()] = &SectionBlock{Last: current, Slice: []Section{current}}
+		} else {
+			block.Last, block.Slice = current, append(block.Slice, current)
+		}
+		for _, n := range section.nodes {
+			if n.typ != nodeKey {
+				continue
+			} else if _, ok := current[n.name]; !ok {
+				current[n.name] = &Value{}
+			}
+			current[n.name].Last, current[n.name].Slice = n.value, append(current[n.name].Slice, n.value)
+		}
+	}
+	return rv
+}
+
+// lineEnd returns the index following the line terminator of the line containing s[n].
+func lineEnd(s string, n int) int {
+	if k := strings.IndexAny(s[n:], "\r\n"); k != -1 {
+		if n += k; strings.HasPrefix(s[n:], "\r\n") {
+			return n + 2
+		}
+		return n + 1
+	}
+	return len(s)
+}
+
+// keyEnd returns the index following the key = value pair that begins s, including the remainder of the line
+// where the value ends.
+func (me Parser) keyEnd(s string) int {
+	end := lineEnd(s, 0)
+	n := strings.IndexFunc(s[:end], me.IsAssign)
+	if n == -1 {
+		return end
+	}
+	n += utf8.RuneLen(firstRune(s[n:]))
+	n += len(s[n:end]) - len(strings.TrimLeft(s[n:end], " \t"))
+	if r := firstRune(s[n:]); me.IsQuote(r) {
+		// The value ends at the closing quotation.
+		if n = me.closeQuote(s, n); n < len(s) {
+			return lineEnd(s, n-1)
+		}
+		return len(s)
+	}
+	// The value ends at the end of the line unless it is continued.
+	for {
+		line := strings.TrimRight(s[n:end], "\r\n")
+		if me.inlineComment(line) != -1 || !me.IsContinue(lastRune(line)) || end == len(s) {
+			return end
+		} else if next := strings.TrimLeft(s[end:lineEnd(s, end)], " \t"); next == "" || next[0] == '\r' || next[0] == '\n' {
+			return end
+		}
+		n, end = end, lineEnd(s, end)
+	}
+}
+
+// closeQuote returns the index following the quotation that begins at s[n] or len(s) if it is not closed.
+func (me Parser) closeQuote(s string, n int) int {
+	quote, size := utf8.DecodeRuneInString(s[n:])
+	raw := me.IsRawQuote(quote)
+	for n += size; n < len(s); {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r == quote {
+			return n + size
+		} else if me.IsEscape(r) && !raw {
+			n += size
+			_, size = utf8.DecodeRuneInString(s[n:])
+		}
+		n += size
+	}
+	return len(s)
+}
+
+// inlineComment returns the index of the inline comment within the unquoted value or -1.
+func (me Parser) inlineComment(value string) int {
+	spaced := false
+	for k, r := range value {
+		if spaced && me.IsInlineComment(r) {
+			return k
+		}
+		spaced = r == ' ' || r == '\t'
+	}
+	return -1
+}
+
+// lastRune returns the last rune in s.
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// String returns the document as configuration text.
+func (me *Document) String() string {
+	s := &strings.Builder{}
+	me.WriteTo(s)
+	return s.String()
+}
+
+// WriteTo writes the document as configuration text to w.
+func (me *Document) WriteTo(w io.Writer) (int64, error) {
+	if me == nil {
+		return 0, errors.NilReceiver()
+	}
+	var rv int64
+	for _, section := range me.sections {
+		nodes := section.nodes
+		if section.header != nil {
+			nodes = append([]*node{section.header}, nodes...)
+		}
+		for _, n := range nodes {
+			size, err := io.WriteString(w, n.raw)
+			if rv += int64(size); err != nil {
+				return rv, errors.Go(err)
+			}
+		}
+	}
+	return rv, nil
+}
+
+// Parsed parses the document.
+func (me *Document) Parsed() (Parsed, error) {
+	if me == nil {
+		return nil, errors.NilReceiver()
+	}
+	return me.parser.Parse(me.String())
+}
+
+// Section returns the last section with the given name or nil if there is no such section; use "" for the
+// global section.
+func (me *Document) Section(name string) *DocumentSection {
+	if sections := me.Sections(name); len(sections) > 0 {
+		return sections[len(sections)-1]
+	}
+	return nil
+}
+
+// Sections returns every section with the given name in the order they appear; use "" for the global section.
+func (me *Document) Sections(name string) []*DocumentSection {
+	var rv []*DocumentSection
+	if me != nil {
+		for _, section := range me.sections {
+			if section.Name() == name {
+				rv = append(rv, section)
+			}
+		}
+	}
+	return rv
+}
+
+// AddSection appends a new section with the given name to the end of the document.
+func (me *Document) AddSection(name string) (*DocumentSection, error) {
+	if me == nil {
+		return nil, errors.NilReceiver()
+	}
+	open, close := firstOf(me.parser.SectionOpen), firstOf(me.parser.SectionClose)
+	n := &node{typ: nodeSection, raw: string(open) + name + string(close) + me.newline()}
+	if err := me.read(n); err != nil {
+		return nil, err
+	} else if n.name != name {
+		return nil, errors.Errorf("Invalid section name= %q", name)
+	}
+	last := me.sections[len(me.sections)-1]
+	if me.terminate() {
+		// Sections are separated by a blank line.
+		last.nodes = append(last.nodes, &node{typ: nodeBlank, raw: me.newline()})
+	}
+	rv := &DocumentSection{doc: me, header: n}
+	me.sections = append(me.sections, rv)
+	return rv, nil
+}
+
+// DeleteSection removes every section with the given name along with its keys and comments; it returns the
+// number of sections removed.  The global section can not be removed.
+func (me *Document) DeleteSection(name string) int {
+	if me == nil || name == "" {
+		return 0
+	}
+	sections := me.sections[:0]
+	for _, section := range me.sections {
+		if section.Name() != name {
+			sections = append(sections, section)
+		}
+	}
+	rv := len(me.sections) - len(sections)
+	me.sections = sections
+	return rv
+}
+
+// RenameSection renames every section with the given name; it returns the number of sections renamed.
+func (me *Document) RenameSection(name, to string) (int, error) {
+	if me == nil {
+		return 0, errors.NilReceiver()
+	}
+	rv := 0
+	for _, section := range me.Sections(name) {
+		if err := section.Rename(to); err != nil {
+			return rv, err
+		}
+		rv++
+	}
+	return rv, nil
+}
+
+// Get returns the last value of key in the last section with the given name and true if the key exists.
+func (me *Document) Get(section, key string) (string, bool) {
+	if s := me.Section(section); s != nil {
+		return s.Get(key)
+	}
+	return "", false
+}
+
+// Set sets key in the last section with the given name to value; the section is added if it does not exist.
+// See DocumentSection.Set.
+func (me *Document) Set(section, key, value string) error {
+	s, err := me.section(section)
+	if err != nil {
+		return err
+	}
+	return s.Set(key, value)
+}
+
+// Append adds another value for key in the last section with the given name; the section is added if it does
+// not exist.  See DocumentSection.Append.
+func (me *Document) Append(section, key, value string) error {
+	s, err := me.section(section)
+	if err != nil {
+		return err
+	}
+	return s.Append(key, value)
+}
+
+// Delete removes key from the last section with the given name; it returns the number of values removed.
+func (me *Document) Delete(section, key string) int {
+	if s := me.Section(section); s != nil {
+		return s.Delete(key)
+	}
+	return 0
+}
+
+// section returns the last section with the given name after adding it if it does not exist.
+func (me *Document) section(name string) (*DocumentSection, error) {
+	if me == nil {
+		return nil, errors.NilReceiver()
+	} else if rv := me.Section(name); rv != nil {
+		return rv, nil
+	}
+	return me.AddSection(name)
+}
+
+// newline returns the line terminator used by the document.
+func (me *Document) newline() string {
+	for _, section := range me.sections {
+		if section.header != nil && strings.HasSuffix(section.header.raw, "\r\n") {
+			return "\r\n"
+		}
+		for _, n := range section.nodes {
+			if strings.HasSuffix(n.raw, "\r\n") {
+				return "\r\n"
+			} else if strings.HasSuffix(n.raw, "\n") {
+				return "\n"
+			}
+		}
+	}
+	return "\n"
+}
+
+// terminate adds a line terminator to the end of the document if it is missing; it returns false if the
+// document is empty.
+func (me *Document) terminate() bool {
+	last := me.sections[len(me.sections)-1]
+	var n *node
+	if size := len(last.nodes); size > 0 {
+		n = last.nodes[size-1]
+	} else if last.header != nil {
+		n = last.header
+	} else {
+		return false
+	}
+	if !strings.HasSuffix(n.raw, "\n") && !strings.HasSuffix(n.raw, "\r") {
+		n.raw = n.raw + me.newline()
+	}
+	return true
+}
+
+// firstOf returns the first rune in runes or 0.
+func firstOf(runes []rune) rune {
+	if len(runes) > 0 {
+		return runes[0]
+	}
+	return 0
+}
+
+// Name returns the name of the section; empty for the global section.
+func (me *DocumentSection) Name() string {
+	if me.header == nil {
+		return ""
+	}
+	return me.header.name
+}
+
+// Get returns the last value of key and true if the key exists.
+func (me *DocumentSection) Get(key string) (string, bool) {
+	values := me.GetAll(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns every value of key in the order they appear.
+func (me *DocumentSection) GetAll(key string) []string {
+	var rv []string
+	for _, n := range me.nodes {
+		if n.typ == nodeKey && n.name == key {
+			rv = append(rv, n.value)
+		}
+	}
+	return rv
+}
+
+// Set sets key to value.  When key is repeated the last occurrence is changed and the others are removed;
+// when key does not exist it is added after the last key in the section.
+//
+// The existing quotation is kept when it can represent value; otherwise value is quoted as necessary.
+func (me *DocumentSection) Set(key, value string) error {
+	last := -1
+	for k, n := range me.nodes {
+		if n.typ == nodeKey && n.name == key {
+			last = k
+		}
+	}
+	if last == -1 {
+		return me.insert(me.lastKey()+1, key, value)
+	}
+	raw, err := me.doc.replaceValue(me.nodes[last], value)
+	if err != nil {
+		return err
+	}
+	me.nodes[last].raw, me.nodes[last].value = raw, value
+	nodes := me.nodes[:0]
+	for k, n := range me.nodes {
+		if k == last || n.typ != nodeKey || n.name != key {
+			nodes = append(nodes, n)
+		}
+	}
+	me.nodes = nodes
+	return nil
+}
+
+// Append adds another value for key after the last occurrence of key or, if key does not exist, after the
+// last key in the section.
+func (me *DocumentSection) Append(key, value string) error {
+	last := -1
+	for k, n := range me.nodes {
+		if n.typ == nodeKey && n.name == key {
+			last = k
+		}
+	}
+	if last == -1 {
+		last = me.lastKey()
+	}
+	return me.insert(last+1, key, value)
+}
+
+// Delete removes every occurrence of key; it returns the number of values removed.
+func (me *DocumentSection) Delete(key string) int {
+	nodes := me.nodes[:0]
+	for _, n := range me.nodes {
+		if n.typ != nodeKey || n.name != key {
+			nodes = append(nodes, n)
+		}
+	}
+	rv := len(me.nodes) - len(nodes)
+	me.nodes = nodes
+	return rv
+}
+
+// Rename renames the section; the global section can not be renamed.
+func (me *DocumentSection) Rename(name string) error {
+	if me.header == nil {
+		return errors.Errorf("The global section can not be renamed")
+	}
+	raw := me.header.raw
+	open := strings.IndexFunc(raw, me.doc.parser.IsOpenSection)
+	n := open + utf8.RuneLen(firstRune(raw[open:]))
+	n += strings.Index(raw[n:], me.header.name)
+	renamed := &node{typ: nodeSection, raw: raw[:n] + name + raw[n+len(me.header.name):]}
+	if err := me.doc.read(renamed); err != nil {
+		return err
+	} else if renamed.name != name {
+		return errors.Errorf("Invalid section name= %q", name)
+	}
+	me.header = renamed
+	return nil
+}
+
+// RenameKey renames every occurrence of key; it returns the number of values renamed.
+func (me *DocumentSection) RenameKey(key, to string) (int, error) {
+	rv := 0
+	for _, n := range me.nodes {
+		if n.typ != nodeKey || n.name != key {
+			continue
+		}
+		k := strings.Index(n.raw, key)
+		renamed := &node{typ: nodeKey, raw: n.raw[:k] + to + n.raw[k+len(key):]}
+		if err := me.doc.read(renamed); err != nil {
+			return rv, err
+		} else if renamed.name != to || renamed.value != n.value {
+			return rv, errors.Errorf("Invalid key name= %q", to)
+		}
+		*n = *renamed
+		rv++
+	}
+	return rv, nil
+}
+
+// lastKey returns the index of the last key in the section or -1.
+func (me *DocumentSection) lastKey() int {
+	rv := -1
+	for k, n := range me.nodes {
+		if n.typ == nodeKey {
+			rv = k
+		}
+	}
+	if rv == -1 && me.header == nil {
+		// Keys added to a global section without keys are placed after any leading comments.
+		for rv+1 < len(me.nodes) && me.nodes[rv+1].typ == nodeComment {
+			rv++
+		}
+	}
+	return rv
+}
+
+// insert adds a new key = value pair at nodes[at].
+func (me *DocumentSection) insert(at int, key, value string) error {
+	indent := ""
+	if at > 0 && me.nodes[at-1].typ == nodeKey {
+		raw := me.nodes[at-1].raw
+		indent = raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+	}
+	assign := firstOf(me.doc.parser.Assign)
+	n := &node{typ: nodeKey, name: key, raw: indent + key + " " + string(assign) + " "}
+	raw, err := me.doc.replaceValue(n, value)
+	if err != nil {
+		return err
+	}
+	n.raw, n.value = raw, value
+	// The preceding line may be the last line of the document without a line terminator.
+	if at > 0 && !strings.HasSuffix(me.nodes[at-1].raw, "\n") && !strings.HasSuffix(me.nodes[at-1].raw, "\r") {
+		me.nodes[at-1].raw += me.doc.newline()
+	} else if at == 0 && me.header != nil && !strings.HasSuffix(me.header.raw, "\n") && !strings.HasSuffix(me.header.raw, "\r") {
+		me.header.raw += me.doc.newline()
+	}
+	me.nodes = append(me.nodes[:at], append([]*node{n}, me.nodes[at:]...)...)
+	return nil
+}
+
+// replaceValue returns the raw text of the key node with its value replaced by value.  The text preceding the
+// value and following it on its last line, such as an inline comment, is kept.
+func (me *Document) replaceValue(n *node, value string) (string, error) {
+	p := me.parser
+	raw := n.raw
+	// The value begins after the assignment and any whitespace.
+	start := strings.IndexFunc(raw, p.IsAssign)
+	if start == -1 {
+		return "", errors.Errorf("Invalid key= %q", n.name)
+	}
+	start += utf8.RuneLen(firstRune(raw[start:]))
+	start += len(raw[start:]) - len(strings.TrimLeft(raw[start:], " \t"))
+	// The value ends at its closing quotation or before trailing whitespace, an inline comment, or the line
+	// terminator of its last line.
+	quote, end := rune(0), len(raw)
+	if r := firstRune(raw[start:]); p.IsQuote(r) {
+		quote, end = r, p.closeQuote(raw, start)
+	} else {
+		lastLine := strings.LastIndexAny(strings.TrimRight(raw, "\r\n"), "\r\n") + 1
+		if lastLine < start {
+			lastLine = start
+		}
+		line := strings.TrimRight(raw[lastLine:], "\r\n")
+		if k := p.inlineComment(line); k != -1 {
+			line = line[:k]
+		}
+		end = lastLine + len(strings.TrimRight(line, " \t"))
+	}
+	prefix, suffix := raw[:start], raw[end:]
+	if suffix == "" {
+		suffix = me.newline()
+	}
+	//
+	for _, candidate := range p.quotations(value, quote) {
+		replaced := &node{typ: nodeKey, raw: prefix + candidate + suffix}
+		if err := me.read(replaced); err == nil && replaced.name == n.name && replaced.value == value &&
+			p.keyEnd(replaced.raw) == len(replaced.raw) {
+			return replaced.raw, nil
+		}
+	}
+	return "", errors.Errorf("Value can not be represented for key %v= %q", n.name, value)
+}
+
+// quotations returns ways to write value in order of preference; prefer is the quotation rune used by the
+// existing value or 0 when the existing value is not quoted.
+func (me Parser) quotations(value string, prefer rune) []string {
+	var rv []string
+	if prefer == 0 {
+		rv = append(rv, value)
+	}
+	quotes := append([]rune{}, me.Quote...)
+	if prefer != 0 {
+		quotes = append([]rune{prefer}, quotes...)
+	}
+	for _, q := range quotes {
+		rv = append(rv, fmt.Sprintf("%c%v%c", q, value, q))
+		if escape := firstOf(me.Escape); escape != 0 && !me.IsRawQuote(q) {
+			escaped := strings.NewReplacer(string(escape), string(escape)+string(escape), string(q), string(escape)+string(q)).Replace(value)
+			rv = append(rv, fmt.Sprintf("%c%v%c", q, escaped, q))
+		}
+	}
+	if prefer != 0 {
+		rv = append(rv, value)
+	}
+	return rv
+}
@@ -0,0 +1,178 @@
+package parser_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestDocument_RoundTrip(t *testing.T) {
+	chk := assert.New(t)
+	//
+	inputs := []string{
+		"",
+		"\n\n",
+		"key = 'quoted'",
+		"\uFEFF# comment\r\nkey = value\r\n",
+		`
+# Leading comment.
+version = 1.0.0   
+  indented key   =   spaced value
+
+; Another comment.
+[ server ]
+listen = :80
+quoted = "multi
+line ' value"
+
+[server]
+listen = :81
+`,
+	}
+	for _, input := range inputs {
+		doc, err := parser.DefaultParser.ParseDocument(input)
+		chk.NoError(err)
+		chk.Equal(input, doc.String())
+		buf := &bytes.Buffer{}
+		n, err := doc.WriteTo(buf)
+		chk.NoError(err)
+		chk.Equal(int64(len(input)), n)
+		chk.Equal(input, buf.String())
+	}
+	{
+		// Optional features.
+		p := parser.DefaultParser
+		p.Escape, p.Continue, p.InlineComment, p.Include, p.Interpolate = []rune{'\\'}, []rune{'\\'}, []rune{'#'}, "include", true
+		input := "include = other.conf\nname = ${missing}\nlong = one \\\n  two \\\n  three # comment\nescaped = \"a \\\" b\" # comment\n"
+		doc, err := p.ParseDocument(input)
+		chk.NoError(err)
+		chk.Equal(input, doc.String())
+		value, ok := doc.Get("", "long")
+		chk.True(ok)
+		chk.Equal("one two three", value)
+		value, _ = doc.Get("", "escaped")
+		chk.Equal(`a " b`, value)
+		value, _ = doc.Get("", "include")
+		chk.Equal("other.conf", value)
+		//
+		chk.NoError(doc.Set("", "long", "four"))
+		chk.NoError(doc.Set("", "escaped", `it's "x"`))
+		chk.Equal("include = other.conf\nname = ${missing}\nlong = four # comment\nescaped = \"it's \\\"x\\\"\" # comment\n", doc.String())
+	}
+	{
+		// Errors.
+		_, err := parser.DefaultParser.ParseDocument("[section")
+		chk.Error(err)
+		_, err = parser.DefaultParser.ParseDocument("a = 'x' b = y\n")
+		chk.Error(err)
+	}
+}
+
+func TestDocument_Edit(t *testing.T) {
+	chk := assert.New(t)
+	//
+	input := `# Header comment.
+
+version = 1.0.0 ; not a comment
+hosts = a
+hosts = b
+
+# Database settings.
+[database]
+	host = localhost
+	password = 'secret'
+
+[server]
+listen = :80
+
+[server]
+listen = :81
+`
+	doc, err := parser.DefaultParser.ParseDocument(input)
+	chk.NoError(err)
+	{
+		chk.NoError(doc.Set("", "version", "1.0.1"))
+		chk.NoError(doc.Set("database", "password", "it's new"))
+		chk.NoError(doc.Set("database", "port", "5432"))
+		chk.NoError(doc.Append("", "hosts", "c"))
+		chk.NoError(doc.Set("cache", "size", " 10 "))
+		chk.Equal(3, doc.Delete("", "hosts"))
+		chk.Equal(0, doc.Delete("missing", "hosts"))
+		chk.NoError(doc.Append("", "hosts", "d"))
+		//
+		servers := doc.Sections("server")
+		chk.Equal(2, len(servers))
+		chk.NoError(servers[0].Set("listen", ":8080"))
+		n, err := servers[1].RenameKey("listen", "bind")
+		chk.NoError(err)
+		chk.Equal(1, n)
+		n, err = doc.RenameSection("database", "db")
+		chk.NoError(err)
+		chk.Equal(1, n)
+		chk.Equal([]string{"d"}, doc.Section("").GetAll("hosts"))
+		chk.Nil(doc.Section("database"))
+		value, ok := doc.Get("db", "password")
+		chk.True(ok)
+		chk.Equal("it's new", value)
+	}
+	chk.Equal(`# Header comment.
+
+version = 1.0.1
+hosts = d
+
+# Database settings.
+[db]
+	host = localhost
+	password = "it's new"
+	port = 5432
+
+[server]
+listen = :8080
+
+[server]
+bind = :81
+
+[cache]
+size = ' 10 '
+`, doc.String())
+	{
+		chk.Equal(2, doc.DeleteSection("server"))
+		chk.Equal(0, doc.DeleteSection(""))
+		parsed, err := doc.Parsed()
+		chk.NoError(err)
+		chk.Equal(map[string][]map[string][]string{
+			"":      {{"version": {"1.0.1"}, "hosts": {"d"}}},
+			"db":    {{"host": {"localhost"}, "password": {"it's new"}, "port": {"5432"}}},
+			"cache": {{"size": {" 10 "}}},
+		}, parsed.Map())
+	}
+	{
+		// Invalid names and values.
+		chk.Error(doc.Set("", "bad key=", "value"))
+		chk.Error(doc.Set("", "key", "'\"`\n"))
+		_, err = doc.AddSection("bad]")
+		chk.Error(err)
+		_, err = doc.RenameSection("db", "")
+		chk.Error(err)
+		chk.Error(doc.Section("").Rename("global"))
+	}
+	{
+		// Keys added to documents without line terminators or keys.
+		doc, err := parser.DefaultParser.ParseDocument("# comment\n\n[section]")
+		chk.NoError(err)
+		chk.NoError(doc.Set("", "a", "1"))
+		chk.NoError(doc.Set("section", "b", "2"))
+		chk.Equal("# comment\na = 1\n\n[section]\nb = 2\n", doc.String())
+		doc, err = parser.DefaultParser.ParseDocument("a = 'x'")
+		chk.NoError(err)
+		chk.NoError(doc.Append("", "a", "y"))
+		chk.Equal("a = 'x'\na = y\n", doc.String())
+		doc, err = parser.DefaultParser.ParseDocument("")
+		chk.NoError(err)
+		chk.NoError(doc.Set("section", "key", "value"))
+		chk.Equal("[section]\nkey = value\n", doc.String())
+	}
+}
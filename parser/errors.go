@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is returned from Parser when the input is not valid configuration; use errors.As to
+// inspect it.
+type SyntaxError struct {
+	// Position is where the error occurred.
+	Position
+	// Excerpt is the line of input containing Position without its line terminator.
+	Excerpt string
+	// Message describes the error.
+	Message string
+	// Err is the underlying error, if any, such as an error opening an included file.
+	Err error
+}
+
+// Unwrap returns the underlying error.
+func (me *SyntaxError) Unwrap() error {
+	return me.Err
+}
+
+// Error returns the error as a string; when Excerpt is not empty it is appended on the following
+// line with a marker beneath the offending column.
+func (me *SyntaxError) Error() string {
+	msg := me.Position.String() + ": " + me.Message
+	if me.Excerpt == "" {
+		return msg
+	}
+	// The marker copies tabs from the excerpt so it aligns regardless of tab width.
+	marker := &strings.Builder{}
+	for k, r := range []rune(me.Excerpt) {
+		if k >= me.Column-1 {
+			break
+		} else if r == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	return fmt.Sprintf("%v\n\t%v\n\t%v^", msg, me.Excerpt, marker.String())
+}
@@ -0,0 +1,116 @@
+package parser_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestSyntaxError(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type Test struct {
+		Name    string
+		Input   string
+		Line    int
+		Column  int
+		Excerpt string
+	}
+	tests := []Test{
+		{
+			Name:    "key whitespace",
+			Input:   "a = b\nkey   . = value1\n",
+			Line:    2,
+			Column:  7,
+			Excerpt: "key   . = value1",
+		},
+		{
+			Name:    "key punctuation",
+			Input:   "a = b\r\n\r\n\tkey. = value1\r\n",
+			Line:    3,
+			Column:  6,
+			Excerpt: "\tkey. = value1",
+		},
+		{
+			Name:    "section punctuation",
+			Input:   "[ a ]\n[ hello.. ]\n",
+			Line:    2,
+			Column:  9,
+			Excerpt: "[ hello.. ]",
+		},
+		{
+			Name:    "unterminated quotation",
+			Input:   "a = b\nc = 'hello\nworld\n",
+			Line:    2,
+			Column:  5,
+			Excerpt: "c = 'hello",
+		},
+		{
+			Name:    "eof in section",
+			Input:   "a = b\n[ section",
+			Line:    2,
+			Column:  10,
+			Excerpt: "[ section",
+		},
+	}
+	for _, test := range tests {
+		_, err := parser.DefaultParser.Parse(test.Input)
+		chk.Error(err, test.Name)
+		var syntaxErr *parser.SyntaxError
+		if chk.True(errors.As(err, &syntaxErr), test.Name) {
+			chk.Equal("", syntaxErr.File, test.Name)
+			chk.Equal(test.Line, syntaxErr.Line, test.Name)
+			chk.Equal(test.Column, syntaxErr.Column, test.Name)
+			chk.Equal(test.Excerpt, syntaxErr.Excerpt, test.Name)
+		}
+	}
+}
+
+func TestSyntaxError_Error(t *testing.T) {
+	chk := assert.New(t)
+	//
+	err := &parser.SyntaxError{
+		Position: parser.Position{File: "app.conf", Line: 3, Column: 7},
+		Excerpt:  "\tkey . = value",
+		Message:  "Parsing key name; unexpected token= .",
+	}
+	chk.Equal("app.conf:3:7: Parsing key name; unexpected token= .\n\t\tkey . = value\n\t\t     ^", err.Error())
+	//
+	err.Excerpt = ""
+	chk.Equal("app.conf:3:7: Parsing key name; unexpected token= .", err.Error())
+	//
+	err.File = ""
+	chk.Equal("3:7: Parsing key name; unexpected token= .", err.Error())
+}
+
+func TestParserParseFile(t *testing.T) {
+	chk := assert.New(t)
+	//
+	tmpfile, err := ioutil.TempFile("", "gotest")
+	chk.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.Write([]byte("[main]\nhello = world\nkey. = oops\n"))
+	chk.NoError(err)
+	chk.NoError(tmpfile.Close())
+	//
+	parsed, err := parser.DefaultParser.ParseFile(tmpfile.Name())
+	chk.Equal(parser.Position{File: tmpfile.Name(), Line: 1, Column: 1}, parsed["main"].Positions[0])
+	chk.Equal(parser.Position{File: tmpfile.Name(), Line: 2, Column: 1}, parsed["main"].Last["hello"].Positions[0])
+	var syntaxErr *parser.SyntaxError
+	if chk.True(errors.As(err, &syntaxErr)) {
+		chk.Equal(tmpfile.Name(), syntaxErr.File)
+		chk.Equal(3, syntaxErr.Line)
+		chk.Equal(5, syntaxErr.Column)
+		chk.True(strings.HasPrefix(err.Error(), tmpfile.Name()+":3:5: "))
+	}
+	//
+	_, err = parser.DefaultParser.ParseFile("asldjflaksdjflaksjflasjdf")
+	chk.Error(err)
+	chk.False(errors.As(err, &syntaxErr))
+}
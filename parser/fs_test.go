@@ -0,0 +1,57 @@
+package parser_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestParserParseFS(t *testing.T) {
+	chk := assert.New(t)
+	//
+	fsys := fstest.MapFS{
+		"app.conf":        {Data: []byte("name = app\ninclude = conf.d/*.conf\ninclude = /shared.conf\n")},
+		"conf.d/a.conf":   {Data: []byte("a = 1\n")},
+		"conf.d/b.conf":   {Data: []byte("[section]\nb = 2\n")},
+		"shared.conf":     {Data: []byte("shared = yes\n")},
+		"cycle.conf":      {Data: []byte("include = cycle.conf\n")},
+		"bad.conf":        {Data: []byte("include = sub/broken.conf\n")},
+		"sub/broken.conf": {Data: []byte("ok = 1\nbad = 'unterminated\n")},
+		"missing.conf":    {Data: []byte("include = nothere.conf\n")},
+	}
+	p := parser.DefaultParser
+	p.Include = "include"
+	{
+		parsed, err := p.ParseFS(fsys, "app.conf")
+		chk.NoError(err)
+		chk.Equal(map[string][]map[string][]string{
+			"":        {{"name": {"app"}, "a": {"1"}, "shared": {"yes"}}},
+			"section": {{"b": {"2"}}},
+		}, parsed.Map())
+		chk.Equal(parser.Position{File: "conf.d/b.conf", Line: 2, Column: 1}, parsed["section"].Last["b"].Positions[0])
+	}
+	{
+		_, err := p.ParseFS(fsys, "cycle.conf")
+		chk.Error(err)
+		chk.Contains(err.Error(), "Include cycle; cycle.conf -> cycle.conf")
+		//
+		_, err = p.ParseFS(fsys, "bad.conf")
+		var syntaxErr *parser.SyntaxError
+		chk.True(errors.As(err, &syntaxErr))
+		chk.Equal(parser.Position{File: "sub/broken.conf", Line: 2, Column: 7}, syntaxErr.Position)
+		//
+		_, err = p.ParseFS(fsys, "missing.conf")
+		chk.True(errors.As(err, &syntaxErr))
+		chk.True(errors.Is(err, fs.ErrNotExist))
+		//
+		_, err = p.ParseFS(fsys, "nothere.conf")
+		chk.Error(err)
+		_, err = p.ParseFS(nil, "app.conf")
+		chk.Error(err)
+	}
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// include parses the files matched by pattern and merges them into rv; values in the global section of an
+// included file are merged into current.  A relative pattern is relative to the directory of file and
+// including is the chain of files currently being parsed.  When fsys is not nil the files are within fsys.
+func (me Parser) include(rv Parsed, current Section, pattern string, fsys fs.FS, file string, including []string) error {
+	var names []string
+	var err error
+	if fsys == nil {
+		names, err = includeNames(pattern, file)
+	} else {
+		names, err = includeNamesFS(fsys, pattern, file)
+	}
+	if err != nil {
+		return err
+	}
+	//
+	for _, name := range names {
+		id := name
+		if fsys == nil {
+			if id, err = filepath.Abs(name); err != nil {
+				return errors.Go(err)
+			}
+		}
+		for _, parent := range including {
+			if parent == id {
+				return errors.Errorf("Include cycle; %v", strings.Join(append(including, id), " -> "))
+			}
+		}
+		parsed, err := me.parseFile(fsys, name, append(including, id))
+		if err != nil {
+			return err
+		}
+		merge(rv, current, parsed)
+	}
+	return nil
+}
+
+// includeNames returns the names of the files matched by pattern in the operating system's file system.
+func includeNames(pattern string, file string) ([]string, error) {
+	if !filepath.IsAbs(pattern) && file != "" {
+		pattern = filepath.Join(filepath.Dir(file), pattern)
+	}
+	if strings.ContainsAny(pattern, `*?[`) {
+		names, err := filepath.Glob(pattern)
+		return names, errors.Go(err)
+	}
+	return []string{pattern}, nil
+}
+
+// includeNamesFS returns the names of the files matched by pattern in fsys; patterns beginning with / are
+// relative to the root of fsys.
+func includeNamesFS(fsys fs.FS, pattern string, file string) ([]string, error) {
+	if strings.HasPrefix(pattern, "/") {
+		pattern = path.Clean(strings.TrimLeft(pattern, "/"))
+	} else {
+		pattern = path.Join(path.Dir(file), pattern)
+	}
+	if strings.ContainsAny(pattern, `*?[`) {
+		names, err := fs.Glob(fsys, pattern)
+		return names, errors.Go(err)
+	}
+	return []string{pattern}, nil
+}
+
+// merge appends the sections and values of src into dst; values in the global section of src are appended
+// to the values in into.
+func merge(dst Parsed, into Section, src Parsed) {
+	for name, block := range src {
+		if name == "" {
+			for _, section := range block.Slice {
+				for key, value := range section {
+					if _, ok := into[key]; !ok {
+						into[key] = &Value{}
+					}
+					into[key].Last = value.Last
+					into[key].Slice = append(into[key].Slice, value.Slice...)
+					into[key].Positions = append(into[key].Positions, value.Positions...)
+				}
+			}
+		} else if existing, ok := dst[name]; !ok {
+			dst[name] = block
+		} else {
+			existing.Last = block.Last
+			existing.Slice = append(existing.Slice, block.Slice...)
+			existing.Positions = append(existing.Positions, block.Positions...)
+			existing.Subsections = append(existing.Subsections, block.Subsections...)
+		}
+	}
+}
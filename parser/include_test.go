@@ -0,0 +1,140 @@
+package parser_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+// writeFiles writes the map of name=content into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParserInclude(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.conf": `name = app
+include = shared.conf
+[ color ]
+name = red
+include = conf.d/*.conf
+[ color ]
+name = blue
+`,
+		"shared.conf": `level = shared
+name = shared
+[ color ]
+name = green
+`,
+		"conf.d/10-first.conf": `port = 80
+`,
+		"conf.d/20-second.conf": `port = 8080
+[ color ]
+name = purple
+`,
+		"conf.d/README": "not included",
+	})
+	//
+	p := parser.DefaultParser
+	p.Include = "include"
+	parsed, err := p.ParseFile(filepath.Join(dir, "app.conf"))
+	chk.NoError(err)
+	//
+	global := parsed[""].Last
+	chk.Equal([]string{"app", "shared"}, global["name"].Slice)
+	chk.Equal("shared", global["level"].Last)
+	chk.Nil(global["include"])
+	chk.Equal(filepath.Join(dir, "shared.conf"), global["name"].Positions[1].File)
+	//
+	colors := parsed["color"]
+	if chk.Equal(4, len(colors.Slice)) {
+		chk.Equal("green", colors.Slice[0]["name"].Last)
+		chk.Equal("red", colors.Slice[1]["name"].Last)
+		chk.Equal("purple", colors.Slice[2]["name"].Last)
+		chk.Equal("blue", colors.Slice[3]["name"].Last)
+		chk.Equal(colors.Slice[3], colors.Last)
+		chk.Equal(4, len(colors.Positions))
+		chk.Equal(filepath.Join(dir, "conf.d/20-second.conf"), colors.Positions[2].File)
+	}
+	// Included global keys are added to the section containing the directive.
+	chk.Equal([]string{"80", "8080"}, colors.Slice[1]["port"].Slice)
+	//
+	// Without Include the directive is a value.
+	parsed, err = parser.DefaultParser.ParseFile(filepath.Join(dir, "app.conf"))
+	chk.NoError(err)
+	chk.Equal("shared.conf", parsed[""].Last["include"].Last)
+}
+
+func TestParserIncludeErrors(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"cycle-a.conf":      "include = cycle-b.conf\n",
+		"cycle-b.conf":      "a = b\ninclude = cycle-a.conf\n",
+		"missing.conf":      "a = b\n  include = does-not-exist.conf\n",
+		"glob.conf":         "include = none/*.conf\n",
+		"bad-glob.conf":     "include = [\n",
+		"syntax.conf":       "include = sub/bad.conf\n",
+		"sub/bad.conf":      "a = b\nkey. = oops\n",
+		"relative.conf":     "include = sub/relative.conf\n",
+		"sub/relative.conf": "include = ../glob.conf\nok = yes\n",
+	})
+	p := parser.DefaultParser
+	p.Include = "include"
+	//
+	var syntaxErr *parser.SyntaxError
+	{
+		_, err := p.ParseFile(filepath.Join(dir, "cycle-a.conf"))
+		if chk.True(errors.As(err, &syntaxErr)) {
+			chk.Equal(filepath.Join(dir, "cycle-b.conf"), syntaxErr.File)
+			chk.Equal(2, syntaxErr.Line)
+			chk.Contains(syntaxErr.Message, "Include cycle")
+		}
+	}
+	{
+		_, err := p.ParseFile(filepath.Join(dir, "missing.conf"))
+		if chk.True(errors.As(err, &syntaxErr)) {
+			chk.Equal(parser.Position{File: filepath.Join(dir, "missing.conf"), Line: 2, Column: 3}, syntaxErr.Position)
+			chk.Equal("  include = does-not-exist.conf", syntaxErr.Excerpt)
+			chk.True(errors.Is(err, os.ErrNotExist))
+		}
+	}
+	{
+		parsed, err := p.ParseFile(filepath.Join(dir, "glob.conf"))
+		chk.NoError(err)
+		chk.Equal(1, len(parsed))
+	}
+	{
+		_, err := p.ParseFile(filepath.Join(dir, "bad-glob.conf"))
+		chk.True(errors.Is(err, filepath.ErrBadPattern))
+	}
+	{
+		_, err := p.ParseFile(filepath.Join(dir, "syntax.conf"))
+		if chk.True(errors.As(err, &syntaxErr)) {
+			chk.Equal(parser.Position{File: filepath.Join(dir, "sub/bad.conf"), Line: 2, Column: 5}, syntaxErr.Position)
+		}
+	}
+	{
+		parsed, err := p.ParseFile(filepath.Join(dir, "relative.conf"))
+		chk.NoError(err)
+		chk.Equal("yes", parsed[""].Last["ok"].Last)
+	}
+}
@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// interpolator expands references within a Parsed.
+type interpolator struct {
+	parsed Parsed
+	// The expanded Last string of values that have been referenced.
+	expanded map[*Value]string
+	// Values whose Last string is currently being expanded and the chain of references that led to them.
+	active map[*Value]bool
+	chain  []string
+}
+
+// Interpolate expands references within every value:
+//	${key}          the value of key in the same section or, if not found there, in the global section
+//	${section.key}  the value of key in the named section
+//	${env:NAME}     the value of the environment variable NAME
+//	$$              a literal $
+//
+// When a referenced key or section is repeated the last value is used.  Section and key names may contain
+// periods; ${a.b.c} is first tried as key a.b.c and then as each possible section and key pair from left to right.
+//
+// Unknown references, unset environment variables, and reference cycles are returned as *SyntaxError.
+func (me Parsed) Interpolate() error {
+	in := &interpolator{parsed: me, expanded: map[*Value]string{}, active: map[*Value]bool{}}
+	// Values are expanded in a consistent order so the same error is returned for the same input; results
+	// are assigned once all expansions complete so references always see the original strings.
+	type result struct {
+		value *Value
+		slice []string
+	}
+	var results []result
+	names := make([]string, 0, len(me))
+	for name := range me {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, section := range me[name].Slice {
+			keys := make([]string, 0, len(section))
+			for key := range section {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				value := section[key]
+				slice := make([]string, len(value.Slice))
+				for k, str := range value.Slice {
+					expanded, err := in.expand(str, section, value.position(k))
+					if err != nil {
+						return err
+					}
+					slice[k] = expanded
+				}
+				results = append(results, result{value, slice})
+			}
+		}
+	}
+	for _, result := range results {
+		result.value.Slice = result.slice
+		if size := len(result.slice); size > 0 {
+			result.value.Last = result.slice[size-1]
+		}
+	}
+	return nil
+}
+
+// position returns Positions[k] if it exists.
+func (me *Value) position(k int) Position {
+	if k < len(me.Positions) {
+		return me.Positions[k]
+	}
+	return Position{}
+}
+
+// expand expands the references in s; section is the section containing s and pos is the position of s.
+func (me *interpolator) expand(s string, section Section, pos Position) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	rv := &strings.Builder{}
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '$')
+		if n == -1 || n == len(s)-1 {
+			rv.WriteString(s)
+			break
+		}
+		rv.WriteString(s[:n])
+		switch s[n+1] {
+		case '$':
+			rv.WriteByte('$')
+			s = s[n+2:]
+		case '{':
+			end := strings.IndexByte(s[n:], '}')
+			if end == -1 {
+				return "", &SyntaxError{Position: pos, Message: fmt.Sprintf("Interpolating value; unterminated reference %v", s[n:])}
+			}
+			str, err := me.lookup(s[n+2:n+end], section, pos)
+			if err != nil {
+				return "", err
+			}
+			rv.WriteString(str)
+			s = s[n+end+1:]
+		default:
+			rv.WriteByte('$')
+			s = s[n+1:]
+		}
+	}
+	return rv.String(), nil
+}
+
+// lookup returns the expanded string for the reference; section is the section containing the reference and
+// pos is the position of the value containing the reference.
+func (me *interpolator) lookup(ref string, section Section, pos Position) (string, error) {
+	if strings.HasPrefix(ref, "env:") {
+		if str, ok := os.LookupEnv(ref[len("env:"):]); ok {
+			return str, nil
+		}
+		return "", &SyntaxError{Position: pos, Message: fmt.Sprintf("Interpolating value; environment variable not set ${%v}", ref)}
+	}
+	//
+	value, owner := me.find(ref, section)
+	if value == nil {
+		return "", &SyntaxError{Position: pos, Message: fmt.Sprintf("Interpolating value; unknown reference ${%v}", ref)}
+	} else if str, ok := me.expanded[value]; ok {
+		return str, nil
+	} else if me.active[value] {
+		return "", &SyntaxError{Position: pos, Message: fmt.Sprintf("Interpolating value; reference cycle %v -> ${%v}", strings.Join(me.chain, " -> "), ref)}
+	}
+	//
+	me.active[value], me.chain = true, append(me.chain, "${"+ref+"}")
+	defer func() {
+		me.active[value], me.chain = false, me.chain[:len(me.chain)-1]
+	}()
+	last := len(value.Slice) - 1
+	str, err := me.expand(value.Slice[last], owner, value.position(last))
+	if err != nil {
+		return "", err
+	}
+	me.expanded[value] = str
+	return str, nil
+}
+
+// find returns the value for the reference and the section that contains it; section is the section
+// containing the reference.
+func (me *interpolator) find(ref string, section Section) (*Value, Section) {
+	if value, ok := section[ref]; ok && len(value.Slice) > 0 {
+		return value, section
+	} else if global, ok := me.parsed[""]; ok {
+		if value, ok := global.Last[ref]; ok && len(value.Slice) > 0 {
+			return value, global.Last
+		}
+	}
+	for n := 0; n < len(ref); n++ {
+		if ref[n] != '.' {
+			continue
+		} else if block, ok := me.parsed[ref[:n]]; ok && block.Last != nil {
+			if value, ok := block.Last[ref[n+1:]]; ok && len(value.Slice) > 0 {
+				return value, block.Last
+			}
+		}
+	}
+	return nil, nil
+}
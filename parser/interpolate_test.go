@@ -0,0 +1,87 @@
+package parser_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestParsedInterpolate(t *testing.T) {
+	chk := assert.New(t)
+	//
+	os.Setenv("CONF_TEST_HOME", "/home/conf")
+	defer os.Unsetenv("CONF_TEST_HOME")
+	//
+	p := parser.DefaultParser
+	p.Interpolate = true
+	parsed, err := p.Parse(`
+base = /opt/app
+host = example.com
+home = ${env:CONF_TEST_HOME}
+price = $$5 and $ alone and trailing $
+url = https://${web.name}:${web.port}/
+[ web ]
+name = ${host}
+port = 8080
+root = ${base}/www
+[ paths.x ]
+logs.dir = ${logs}
+logs = ${base}/logs
+[ paths.x ]
+logs = ${base}/logs2
+logs.dir = ${logs}/dir
+[ other ]
+dotted = ${paths.x.logs.dir}
+repeated = ${paths.x.logs}
+list = ${host}
+list = ${web.port}
+`)
+	chk.NoError(err)
+	global := parsed[""].Last
+	chk.Equal("/home/conf", global["home"].Last)
+	chk.Equal("$5 and $ alone and trailing $", global["price"].Last)
+	chk.Equal("https://example.com:8080/", global["url"].Last)
+	chk.Equal("example.com", parsed["web"].Last["name"].Last)
+	chk.Equal("/opt/app/www", parsed["web"].Last["root"].Last)
+	chk.Equal("/opt/app/logs", parsed["paths.x"].Slice[0]["logs.dir"].Last)
+	chk.Equal("/opt/app/logs2", parsed["other"].Last["repeated"].Last)
+	chk.Equal("/opt/app/logs2/dir", parsed["other"].Last["dotted"].Last)
+	chk.Equal([]string{"example.com", "8080"}, parsed["other"].Last["list"].Slice)
+	chk.Equal("8080", parsed["other"].Last["list"].Last)
+	//
+	// Interpolation is opt-in.
+	parsed, err = parser.DefaultParser.Parse("a = ${b}\n")
+	chk.NoError(err)
+	chk.Equal("${b}", parsed[""].Last["a"].Last)
+}
+
+func TestParsedInterpolateErrors(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type Test struct {
+		Input   string
+		Line    int
+		Message string
+	}
+	tests := []Test{
+		{"a = b\nc = ${missing}\n", 2, "Interpolating value; unknown reference ${missing}"},
+		{"a = ${env:CONF_TEST_NOT_SET}\n", 1, "Interpolating value; environment variable not set ${env:CONF_TEST_NOT_SET}"},
+		{"a = ${b\n", 1, "Interpolating value; unterminated reference ${b"},
+		{"a = ${a}\n", 1, "Interpolating value; reference cycle ${a} -> ${a}"},
+		{"a = ${b}\nb = ${s.c}\n[s]\nc = ${a}\n", 1, "Interpolating value; reference cycle ${b} -> ${s.c} -> ${a} -> ${b}"},
+	}
+	p := parser.DefaultParser
+	p.Interpolate = true
+	for _, test := range tests {
+		_, err := p.Parse(test.Input)
+		var syntaxErr *parser.SyntaxError
+		if chk.True(errors.As(err, &syntaxErr), test.Input) {
+			chk.Equal(test.Line, syntaxErr.Line, test.Input)
+			chk.Equal(test.Message, syntaxErr.Message, test.Input)
+		}
+	}
+}
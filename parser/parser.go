@@ -1,9 +1,16 @@
 package parser
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/nofeaturesonlybugs/errors"
 )
@@ -48,6 +55,22 @@ type Runes struct {
 	// Any rune present in Quote acts as a quotation rune; quoted values must use the same rune
 	// to start and end the quotation.
 	Quote []rune
+	// Any rune present in Escape begins an escape sequence within a value; see Parser for the supported
+	// sequences.  When Escape is empty values are never unescaped.
+	Escape []rune
+	// Any rune present in both Quote and RawQuote begins a raw quotation; escape sequences within raw
+	// quotations are not processed.
+	RawQuote []rune
+	// Any rune present in Continue that is the last rune on a line continues an unquoted value onto the
+	// next line; see Parser for details.
+	Continue []rune
+	// Any rune present in Comment begins a comment line; when Comment is empty any punctuation that does
+	// not open a section begins a comment line and when it is not empty any other punctuation at the
+	// beginning of a line is a syntax error.
+	Comment []rune
+	// Any rune present in InlineComment begins a comment that ends an unquoted value when it is preceded
+	// by whitespace; it has no special meaning within quotations.
+	InlineComment []rune
 }
 
 // Within tests if r is within possible.
@@ -80,11 +103,141 @@ func (me Runes) IsQuote(r rune) bool {
 	return me.Within(r, me.Quote)
 }
 
+// IsEscape returns true if the rune begins an escape sequence.
+func (me Runes) IsEscape(r rune) bool {
+	return me.Within(r, me.Escape)
+}
+
+// IsRawQuote returns true if the rune begins a raw quotation.
+func (me Runes) IsRawQuote(r rune) bool {
+	return me.Within(r, me.RawQuote)
+}
+
+// IsContinue returns true if the rune continues a value onto the next line.
+func (me Runes) IsContinue(r rune) bool {
+	return me.Within(r, me.Continue)
+}
+
+// IsComment returns true if the rune begins a comment line; when Comment is empty every rune begins a comment line.
+func (me Runes) IsComment(r rune) bool {
+	return len(me.Comment) == 0 || me.Within(r, me.Comment)
+}
+
+// IsInlineComment returns true if the rune begins an inline comment.
+func (me Runes) IsInlineComment(r rune) bool {
+	return me.Within(r, me.InlineComment)
+}
+
+// firstRune returns the first rune in s.
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// unescape returns the literal string for the token that follows an escape rune.  Only the beginning of
+// the token is part of the escape sequence and the remainder of the token is returned as rest.  ok is false
+// if the token does not begin a valid escape sequence.
+func unescape(str string, tok Token) (lit string, rest string, ok bool) {
+	r, size := utf8.DecodeRuneInString(str)
+	switch tok {
+	case TokenPunct, TokenWhiteSpace:
+		return str[:size], str[size:], true
+	case TokenAlphaNum:
+		switch r {
+		case 'n':
+			return "\n", str[size:], true
+		case 'r':
+			return "\r", str[size:], true
+		case 't':
+			return "\t", str[size:], true
+		case 'u', 'U':
+			digits := 4
+			if r == 'U' {
+				digits = 8
+			}
+			if len(str) < size+digits {
+				return "", "", false
+			}
+			n, err := strconv.ParseUint(str[size:size+digits], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(n)) {
+				return "", "", false
+			}
+			return string(rune(n)), str[size+digits:], true
+		}
+	}
+	return "", "", false
+}
+
 // Parser parses a string into a Configuration.
+//
+// Include Directives
+//
+// When Include is not empty a key with that name is an include directive rather than a value.  Its value is
+// a file name or glob pattern; relative names are relative to the directory of the including file or the
+// working directory when the input is not a file.  Each matching file is parsed in lexical order and merged
+// as if its contents appeared in place of the directive:
+//	include = shared.conf
+//	include = conf.d/*.conf
+//
+// Keys in the global section of an included file are added to the section containing the directive and sections
+// in the included file are appended to any sections with the same name.  After the directive the including file
+// continues in the section that contained the directive.  Including a file that is already being parsed is
+// an error.
+//
+// Escape Sequences
+//
+// When Runes.Escape is not empty an escape rune within a value begins an escape sequence.  The following
+// sequences are supported where \ is the escape rune:
+//	\n          newline
+//	\r          carriage return
+//	\t          tab
+//	\uXXXX      unicode code point with 4 hexadecimal digits
+//	\UXXXXXXXX  unicode code point with 8 hexadecimal digits
+//	\?          any punctuation or whitespace rune ? is added to the value literally; this
+//	            includes quote runes, escape runes, and leading or trailing whitespace
+//
+// Any other sequence is a syntax error.  Escape sequences are not processed in raw quotations; see Runes.RawQuote.
+//
+// Line Continuation
+//
+// When Runes.Continue is not empty an unquoted value can span multiple lines by ending each line with
+// a continuation rune.  The continuation rune and line break are removed and the leading whitespace of the
+// following line is discarded while whitespace preceding the continuation rune is kept:
+//	flags = -Xms256m \
+//	        -Xmx1g \
+//	        -server
+//
+// is the value "-Xms256m -Xmx1g -server".  A blank line after a continuation ends the value.  When the
+// continuation rune is also an escape rune it can be escaped to end a value with that rune literally.
 type Parser struct {
 	Runes
+	// Include is the key name of include directives; include directives are disabled when empty.
+	Include string
+	// When Interpolate is true references within values are expanded once parsing completes; see
+	// Parsed.Interpolate.
+	Interpolate bool
+	// Subsections determines how section headers name subsections; see SubsectionRule.
+	Subsections SubsectionRule
 }
 
+// SubsectionRule determines how a section header names a subsection such as origin in [remote "origin"].
+// Sections with subsections are stored under the section name and SectionBlock.Subsections records the
+// subsection of each.
+type SubsectionRule int
+
+// Enums for SubsectionRule.
+const (
+	// SubsectionsNone treats the entire section header as the section name.
+	SubsectionsNone SubsectionRule = iota
+	// SubsectionsQuoted names a subsection with a quotation following the section name and whitespace:
+	//	[remote "origin"]
+	SubsectionsQuoted
+	// SubsectionsSpace names a subsection with the remainder of the section header following the first
+	// whitespace or with a quotation as in SubsectionsQuoted:
+	//	[remote origin]
+	SubsectionsSpace
+)
+
 // DefaultParser is a Parser with common settings.
 var DefaultParser = Parser{
 	Runes: Runes{
@@ -97,42 +250,149 @@ var DefaultParser = Parser{
 
 // Parse parses a string.
 func (me Parser) Parse(s string) (Parsed, error) {
+	return me.finish(me.parse(NewTokenizer(s), nil, "", nil))
+}
+
+// finish performs any work that occurs after parsing, such as interpolation, when there is no error.
+func (me Parser) finish(parsed Parsed, err error) (Parsed, error) {
+	if err == nil && me.Interpolate {
+		err = parsed.Interpolate()
+	}
+	return parsed, err
+}
+
+// parse parses the tokens returned from t; fsys is the file system for include directives or nil for the
+// operating system's, file is the name of the input used when reporting errors and resolving include
+// directives, and including is the chain of files currently being parsed.
+func (me Parser) parse(t Tokenizer, fsys fs.FS, file string, including []string) (Parsed, error) {
 	var err error
 	//
+	positions, _ := t.(PositionTokenizer)
+	position := func() Position {
+		if positions == nil {
+			return Position{}
+		}
+		return positions.Position()
+	}
+	excerpt := func() string {
+		if positions == nil {
+			return ""
+		}
+		return positions.Excerpt()
+	}
+	syntaxError := func(pos Position, excerpt string, format string, args ...interface{}) error {
+		pos.File = file
+		return &SyntaxError{Position: pos, Excerpt: excerpt, Message: fmt.Sprintf(format, args...)}
+	}
+	//
 	assign := func(s string, t Token) bool {
-		return t == TokenPunct && me.IsAssign(rune(s[0]))
+		return t == TokenPunct && me.IsAssign(firstRune(s))
 	}
 	quote := func(s string, t Token) bool {
-		return t == TokenPunct && me.IsQuote(rune(s[0]))
+		return t == TokenPunct && me.IsQuote(firstRune(s))
+	}
+	escape := func(s string, t Token) bool {
+		return t == TokenPunct && me.IsEscape(firstRune(s))
+	}
+	continuation := func(s string, t Token) bool {
+		return t == TokenPunct && me.IsContinue(firstRune(s))
+	}
+	comment := func(s string, t Token) bool {
+		return t == TokenPunct && me.IsComment(firstRune(s))
+	}
+	inlineComment := func(s string, t Token) bool {
+		return t == TokenPunct && me.IsInlineComment(firstRune(s))
+	}
+	peekType := func() Token {
+		_, typ := t.Peek()
+		return typ
 	}
 	closeSection := func(s string, t Token) bool {
-		return t == TokenPunct && me.IsCloseSection(rune(s[0]))
+		return t == TokenPunct && me.IsCloseSection(firstRune(s))
 	}
 	openSection := func(s string, t Token) bool {
-		return t == TokenPunct && me.IsOpenSection(rune(s[0]))
+		return t == TokenPunct && me.IsOpenSection(firstRune(s))
 	}
 	//
 	rv := make(Parsed)
 	rv[""] = &SectionBlock{Last: make(Section)} // A default unnamed section.
 	rv[""].Slice = []Section{rv[""].Last}
+	rv[""].Positions = []Position{{File: file, Line: 1, Column: 1}}
+	if me.Subsections != SubsectionsNone {
+		rv[""].Subsections = []string{""}
+	}
 	current := rv[""].Last // Current block to put key=values into.
+	// beginSection makes the named section current.
+	beginSection := func(name, subsection string, pos Position) {
+		current = make(Section)
+		block, ok := rv[name]
+		if !ok {
+			block = &SectionBlock{}
+			rv[name] = block
+		}
+		block.Last = current
+		block.Slice = append(block.Slice, current)
+		block.Positions = append(block.Positions, pos)
+		if me.Subsections != SubsectionsNone {
+			block.Subsections = append(block.Subsections, subsection)
+		}
+	}
+	// quotedSubsection reads the remainder of a quoted subsection that began with open through the end of
+	// the section header.
+	quotedSubsection := func(open string, pos Position, openExcerpt string) (string, error) {
+		rv := ""
+		for !t.EOF() {
+			str, tok := t.Next()
+			if tok == TokenNewline {
+				break
+			} else if str == open {
+				// Only whitespace can follow the quotation before the section header closes.
+				for !t.EOF() {
+					// The excerpt is taken before a newline moves the tokenizer to the next line.
+					next, nextExcerpt := position(), excerpt()
+					if str, tok = t.Next(); closeSection(str, tok) {
+						return rv, nil
+					} else if tok != TokenWhiteSpace {
+						return "", syntaxError(next, nextExcerpt, "Parsing section name; unexpected token= %q", str)
+					}
+				}
+				return "", syntaxError(position(), excerpt(), "Unexpected EOF while parsing %v", StateSection.String())
+			} else if escape(str, tok) && !t.EOF() {
+				str, _ = t.Next()
+			}
+			rv = rv + str
+		}
+		return "", syntaxError(pos, openExcerpt, "Parsing section name; unterminated quotation %v", open)
+	}
 	//
 	section, key, value, previous, quotation := "", "", "", "", ""
+	// Where the current key or section began; keyExcerpt is only set for include directives.
+	keyPos, sectionPos, keyExcerpt := Position{}, Position{}, ""
+	// Where the current quotation began; unterminated quotations are reported at this position.
+	quotationPos, quotationExcerpt := Position{}, ""
+	// True when the previous token in a value was an escape rune as well as the escape rune and where it was.
+	escaped, escapeStr, escapePos, escapeExcerpt := false, "", Position{}, ""
+	// True when the previous token in a value was whitespace that was not escaped.
+	spaced := false
 	//
-	t, st := NewTokenizer(s), StateNone
+	st := StateNone
 	for err == nil && !t.EOF() {
+		pos := position()
+		pos.File = file
 		str, tok := t.Next()
 		switch st {
 		case StateNone:
 			if tok == TokenAlphaNum {
 				// Beginning of key = value
-				st, key, value, previous, quotation = StateKey, str, "", "", ""
+				st, key, value, previous, quotation, keyPos = StateKey, str, "", "", "", pos
 			} else if tok == TokenPunct {
 				// Punctuation is either opening a section or beginning a comment.
 				if openSection(str, tok) {
-					st, section, previous = StateSection, "", ""
-				} else {
+					st, section, previous, sectionPos = StateSection, "", "", pos
+				} else if comment(str, tok) {
 					st = StateComment
+				} else {
+					err = syntaxError(pos, excerpt(), "Unexpected token= %v; expected key, section, or comment", str)
 				}
 			}
 
@@ -148,28 +408,32 @@ func (me Parser) Parse(s string) (Parsed, error) {
 				if section != "" {
 					previous = str
 				}
-				// Whitespace in section name has to be followed by section close or another alphanum.
-				if peek, peekT := t.Peek(); peekT != TokenAlphaNum && !closeSection(peek, peekT) {
-					err = errors.Errorf("Parsing section name; unexpected token= %v", peek)
+				// Whitespace in section name has to be followed by section close, another alphanum, or a quoted
+				// subsection.
+				peek, peekT := t.Peek()
+				subsection := me.Subsections != SubsectionsNone && section != "" && quote(peek, peekT)
+				if peekT != TokenAlphaNum && !closeSection(peek, peekT) && !subsection {
+					err = syntaxError(position(), excerpt(), "Parsing section name; unexpected token= %v", peek)
 				}
 			} else if tok == TokenPunct {
 				if closeSection(str, tok) {
-					current = make(Section)
-					if existing, ok := rv[section]; !ok {
-						rv[section] = &SectionBlock{
-							Last:  current,
-							Slice: []Section{current},
-						}
-					} else {
-						existing.Last = current
-						existing.Slice = append(existing.Slice, current)
+					name, subsection := section, ""
+					if n := strings.IndexAny(section, " \t"); n != -1 && me.Subsections == SubsectionsSpace {
+						name, subsection = section[:n], strings.TrimLeft(section[n:], " \t")
 					}
+					beginSection(name, subsection, sectionPos)
 					st = StateNone
+				} else if me.Subsections != SubsectionsNone && previous != "" && strings.TrimLeft(previous, " \t") == "" && quote(str, tok) {
+					var subsection string
+					if subsection, err = quotedSubsection(str, pos, excerpt()); err == nil {
+						beginSection(section, subsection, sectionPos)
+						st = StateNone
+					}
 				} else {
 					previous = str
 					// Punctuation in section name has to be followed by another alphanum.
 					if peek, peekT := t.Peek(); peekT != TokenAlphaNum {
-						err = errors.Errorf("Parsing section name; unexpected token= %v", peek)
+						err = syntaxError(position(), excerpt(), "Parsing section name; unexpected token= %v", peek)
 					}
 				}
 			}
@@ -183,24 +447,54 @@ func (me Parser) Parse(s string) (Parsed, error) {
 				}
 				// Whitespace in key has to be followed by assign or another alphanum.
 				if peek, peekT := t.Peek(); peekT != TokenAlphaNum && !assign(peek, peekT) {
-					err = errors.Errorf("Parsing key name; unexpected token= %v", peek)
+					err = syntaxError(position(), excerpt(), "Parsing key name; unexpected token= %v", peek)
 				}
 			} else if tok == TokenPunct {
 				if assign(str, tok) {
-					st, previous = StateValue, ""
+					st, previous, spaced = StateValue, "", false
+					if key == me.Include {
+						keyExcerpt = excerpt()
+					}
 				} else {
 					previous = str
 					// Punctuation in key has to be followed by another alphanum.
 					if peek, peekT := t.Peek(); peekT != TokenAlphaNum {
-						err = errors.Errorf("Parsing key name; unexpected token= %v", peek)
+						err = syntaxError(position(), excerpt(), "Parsing key name; unexpected token= %v", peek)
 					}
 				}
 			}
 
 		case StateValue:
-			if tok == TokenPunct && quote(str, tok) {
+			if escaped {
+				// The previous token was an escape rune.
+				lit, rest, ok := unescape(str, tok)
+				if !ok {
+					err = syntaxError(escapePos, escapeExcerpt, "Parsing value; invalid escape sequence= %q", escapeStr+str)
+					break
+				}
+				escaped, value, previous, str = false, value+previous+lit, "", rest
+				if str == "" {
+					spaced = false
+					break
+				}
+				// The remainder of the token is not part of the escape sequence and is handled normally.
+			}
+			if quotation == "" && continuation(str, tok) && peekType() == TokenNewline {
+				// Joins the next line to this value unless it is blank.
+				if breaks, _ := t.Next(); lineBreaks(breaks) > 1 {
+					st = StateNone
+				} else if peekType() == TokenWhiteSpace {
+					t.Next()
+					spaced = true
+					break
+				}
+			} else if escape(str, tok) && (quotation == "" || !me.IsRawQuote(firstRune(quotation))) {
+				escaped, escapeStr, escapePos, escapeExcerpt = true, str, pos, excerpt()
+			} else if quotation == "" && spaced && inlineComment(str, tok) {
+				st = StateComment
+			} else if tok == TokenPunct && quote(str, tok) {
 				if value == "" && quotation == "" {
-					quotation = str
+					quotation, quotationPos, quotationExcerpt = str, pos, excerpt()
 				} else if str != quotation {
 					value, previous = value+previous+str, ""
 				} else {
@@ -222,27 +516,90 @@ func (me Parser) Parse(s string) (Parsed, error) {
 					st = StateNone
 				}
 			}
-			if st == StateNone { // Intentionally not attached to previous if..else block
+			spaced = tok == TokenWhiteSpace
+			if st != StateValue && me.Include != "" && key == me.Include { // Intentionally not attached to previous if..else block
+				// Include directive was completed.
+				if includeErr := me.include(rv, current, value, fsys, file, including); includeErr != nil {
+					if nested, ok := includeErr.(*SyntaxError); ok {
+						err = nested
+					} else {
+						original, _ := errors.Original(includeErr).(error)
+						err = &SyntaxError{Position: keyPos, Excerpt: keyExcerpt, Message: fmt.Sprintf("Including %v; %v", value, includeErr), Err: original}
+					}
+				}
+			} else if st != StateValue {
 				// Value was completed.
 				if _, ok := current[key]; !ok {
 					current[key] = &Value{}
 				}
 				current[key].Last = value
 				current[key].Slice = append(current[key].Slice, value)
+				current[key].Positions = append(current[key].Positions, keyPos)
 			}
 		}
 	}
-	if st != StateNone {
-		return rv, errors.Errorf("Unexpected EOF while parsing %v", st.String())
+	if err == nil && st != StateNone {
+		if quotation != "" {
+			return rv, syntaxError(quotationPos, quotationExcerpt, "Unexpected EOF while parsing %v; unterminated quotation %v", st.String(), quotation)
+		}
+		return rv, syntaxError(position(), excerpt(), "Unexpected EOF while parsing %v", st.String())
 	}
 	return rv, err
 }
 
-// ParseReader parses the reader.
-func (me Parser) ParseReader(r io.Reader) (Parsed, error) {
-	s := &strings.Builder{}
-	if _, err := io.Copy(s, r); err != nil {
+// ParseFile opens and parses the named file; errors returned while parsing are *SyntaxError with the
+// file name recorded in their Position.
+func (me Parser) ParseFile(name string) (Parsed, error) {
+	var including []string
+	if abs, err := filepath.Abs(name); err == nil {
+		including = []string{abs}
+	}
+	return me.finish(me.parseFile(nil, name, including))
+}
+
+// ParseFS opens and parses the named file within fsys; errors returned while parsing are *SyntaxError with
+// the file name recorded in their Position.  Include directives are resolved within fsys.
+func (me Parser) ParseFS(fsys fs.FS, name string) (Parsed, error) {
+	if fsys == nil {
+		return nil, errors.NilArgument("fsys")
+	}
+	return me.finish(me.parseFile(fsys, name, []string{path.Clean(name)}))
+}
+
+// parseFile opens and parses the named file within fsys or the operating system's file system when fsys is
+// nil; including is the chain of files currently being parsed.
+func (me Parser) parseFile(fsys fs.FS, name string, including []string) (Parsed, error) {
+	var handle io.ReadCloser
+	var err error
+	if fsys == nil {
+		handle, err = os.Open(name)
+	} else {
+		handle, err = fsys.Open(name)
+	}
+	if err != nil {
 		return nil, errors.Go(err)
 	}
-	return me.Parse(s.String())
+	defer handle.Close()
+	//
+	return me.parseReader(handle, fsys, name, including)
+}
+
+// ParseReader parses the reader.  The reader is consumed incrementally as it is parsed; if it does not
+// implement io.RuneReader it is wrapped in a bufio.Reader.
+func (me Parser) ParseReader(r io.Reader) (Parsed, error) {
+	return me.finish(me.parseReader(r, nil, "", nil))
+}
+
+// parseReader parses the reader; see parse for fsys, file, and including.
+func (me Parser) parseReader(r io.Reader, fsys fs.FS, file string, including []string) (Parsed, error) {
+	rr, ok := r.(io.RuneReader)
+	if !ok {
+		rr = bufio.NewReader(r)
+	}
+	t := NewReaderTokenizer(rr)
+	parsed, err := me.parse(t, fsys, file, including)
+	if readErr := t.(*readerTokenizer).Err(); readErr != nil {
+		return nil, errors.Go(readErr)
+	}
+	return parsed, err
 }
@@ -1,7 +1,9 @@
 package parser_test
 
 import (
+	stderrors "errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -518,3 +520,274 @@ func TestStateStrings(t *testing.T) {
 	chk.Equal("Value", parser.StateValue.String())
 	chk.Equal(true, strings.HasPrefix(parser.State(-10).String(), "Unknown"))
 }
+
+func TestParserPositions(t *testing.T) {
+	chk := assert.New(t)
+	//
+	parsed, err := parser.DefaultParser.Parse(`hello = world
+[ domain ]
+	listen = 0.0.0.0
+	listen = "example.com"
+
+[ domain ]
+  listen = localhost
+`)
+	chk.NoError(err)
+	//
+	chk.Equal([]parser.Position{{Line: 1, Column: 1}}, parsed[""].Positions)
+	chk.Equal([]parser.Position{{Line: 1, Column: 1}}, parsed[""].Last["hello"].Positions)
+	//
+	block := parsed["domain"]
+	chk.Equal([]parser.Position{{Line: 2, Column: 1}, {Line: 6, Column: 1}}, block.Positions)
+	chk.Equal([]parser.Position{{Line: 3, Column: 2}, {Line: 4, Column: 2}}, block.Slice[0]["listen"].Positions)
+	chk.Equal([]parser.Position{{Line: 7, Column: 3}}, block.Slice[1]["listen"].Positions)
+}
+
+func TestParserUTF8(t *testing.T) {
+	chk := assert.New(t)
+	//
+	{
+		parsed, err := parser.DefaultParser.Parse("\uFEFF" + `
+		clé = valeur
+		straße.name = Müller
+		[ Größe ]
+		höhe = 12 cm
+		[ 日本 語 ]
+		名前 = "  引用された値  "
+		motto = 'Ça va? «Très» bien!'
+	`)
+		chk.NoError(err)
+		chk.Equal("valeur", parsed[""].Last["clé"].Last)
+		chk.Equal("Müller", parsed[""].Last["straße.name"].Last)
+		chk.Equal("12 cm", parsed["Größe"].Last["höhe"].Last)
+		chk.Equal("  引用された値  ", parsed["日本 語"].Last["名前"].Last)
+		chk.Equal("Ça va? «Très» bien!", parsed["日本 語"].Last["motto"].Last)
+		chk.Equal(parser.Position{Line: 7, Column: 3}, parsed["日本 語"].Last["名前"].Positions[0])
+	}
+	{ // Multi-byte runes as parser runes.
+		p := parser.Parser{
+			Runes: parser.Runes{
+				Assign:       []rune{'→'},
+				Quote:        []rune{'§'},
+				SectionOpen:  []rune{'«'},
+				SectionClose: []rune{'»'},
+			},
+		}
+		parsed, err := p.Parse(`
+		«sección»
+		clé → §  quoted «value»  §
+	`)
+		chk.NoError(err)
+		chk.Equal("  quoted «value»  ", parsed["sección"].Last["clé"].Last)
+	}
+}
+
+func TestParserReaderStreaming(t *testing.T) {
+	chk := assert.New(t)
+	//
+	{ // The reader is consumed as it is written.
+		r, w := io.Pipe()
+		go func() {
+			for k := 0; k < 1000; k++ {
+				fmt.Fprintf(w, "[ section ]\nkey = value %v\n", k)
+			}
+			w.Close()
+		}()
+		parsed, err := parser.DefaultParser.ParseReader(r)
+		chk.NoError(err)
+		chk.Equal(1000, len(parsed["section"].Slice))
+		chk.Equal("value 999", parsed["section"].Last["key"].Last)
+		chk.Equal(parser.Position{Line: 2000, Column: 1}, parsed["section"].Last["key"].Positions[0])
+	}
+	{ // Syntax errors are positioned.
+		_, err := parser.DefaultParser.ParseReader(iotest.OneByteReader(strings.NewReader("a = b\n[ hello.. ]\n")))
+		var syntaxErr *parser.SyntaxError
+		if chk.True(stderrors.As(err, &syntaxErr)) {
+			chk.Equal(parser.Position{Line: 2, Column: 9}, syntaxErr.Position)
+			chk.Equal("[ hello.. ]", syntaxErr.Excerpt)
+		}
+	}
+	{ // Read errors after partial input.
+		r := io.MultiReader(strings.NewReader("a = b\n"), iotest.ErrReader(errors.Errorf("kaboom")))
+		parsed, err := parser.DefaultParser.ParseReader(r)
+		chk.Nil(parsed)
+		chk.Error(err)
+	}
+}
+
+func TestParserEscape(t *testing.T) {
+	chk := assert.New(t)
+	//
+	p := parser.DefaultParser
+	p.Escape = []rune{'\\'}
+	p.RawQuote = []rune{'`'}
+	//
+	{
+		parsed, err := p.Parse(`
+		double = "She said \"hi\" \\ left"
+		single = 'It\'s here'
+		controls = "a\tb\nc\rd"
+		unicode = caf\u00e9 \U0001F600\u00e9t\u00e9
+		unquoted = \"not quoted\"
+		spaces = \  padded \ 
+		comment = \# not a comment
+		raw = ` + "`C:\\temp\\new`" + `
+		unaffected = abc
+	`)
+		chk.NoError(err)
+		section := parsed[""].Last
+		chk.Equal(`She said "hi" \ left`, section["double"].Last)
+		chk.Equal(`It's here`, section["single"].Last)
+		chk.Equal("a\tb\nc\rd", section["controls"].Last)
+		chk.Equal("café 😀été", section["unicode"].Last)
+		chk.Equal(`"not quoted"`, section["unquoted"].Last)
+		chk.Equal("  padded  ", section["spaces"].Last)
+		chk.Equal("# not a comment", section["comment"].Last)
+		chk.Equal(`C:\temp\new`, section["raw"].Last)
+		chk.Equal("abc", section["unaffected"].Last)
+	}
+	{ // Without an escape rune backslashes are literal.
+		parsed, err := parser.DefaultParser.Parse(`path = "C:\temp\new"
+`)
+		chk.NoError(err)
+		chk.Equal(`C:\temp\new`, parsed[""].Last["path"].Last)
+	}
+	{ // Invalid sequences
+		type Test struct {
+			Input  string
+			Column int
+		}
+		tests := []Test{
+			{"a = b\\q\n", 6},
+			{"a = \"b\\9\"\n", 7},
+			{"a = b\\u12\n", 6},
+			{"a = b\\u12zz\n", 6},
+			{"a = b\\UFFFFFFFF\n", 6},
+			{"a = b\\\nc\n", 6},
+		}
+		for _, test := range tests {
+			_, err := p.Parse(test.Input)
+			var syntaxErr *parser.SyntaxError
+			if chk.True(stderrors.As(err, &syntaxErr), test.Input) {
+				chk.Equal(parser.Position{Line: 1, Column: test.Column}, syntaxErr.Position, test.Input)
+				chk.Equal(strings.SplitN(test.Input, "\n", 2)[0], syntaxErr.Excerpt, test.Input)
+			}
+		}
+	}
+}
+
+func TestParserContinue(t *testing.T) {
+	chk := assert.New(t)
+	//
+	p := parser.DefaultParser
+	p.Continue = []rune{'\\'}
+	//
+	{
+		parsed, err := p.Parse("flags = -Xms256m \\\n        -Xmx1g \\\r\n\t-server\n" +
+			"joined = abc\\\n    def\n" +
+			"empty = \\\n  value\n" +
+			"blank = abc \\\n\nafter = yes\n" +
+			"quoted = \"abc \\\n  def\"\n" +
+			"middle = a \\ b\n")
+		chk.NoError(err)
+		section := parsed[""].Last
+		chk.Equal("-Xms256m -Xmx1g -server", section["flags"].Last)
+		chk.Equal("abcdef", section["joined"].Last)
+		chk.Equal("value", section["empty"].Last)
+		chk.Equal("abc", section["blank"].Last)
+		chk.Equal("yes", section["after"].Last)
+		chk.Equal("abc \\\n  def", section["quoted"].Last)
+		chk.Equal("a \\ b", section["middle"].Last)
+		chk.Equal(parser.Position{Line: 10, Column: 1}, section["after"].Positions[0])
+	}
+	{ // Without a continuation rune the backslash is part of the value.
+		parsed, err := parser.DefaultParser.Parse("path = C:\\temp\\\nnext = value\n")
+		chk.NoError(err)
+		chk.Equal("C:\\temp\\", parsed[""].Last["path"].Last)
+		chk.Equal("value", parsed[""].Last["next"].Last)
+	}
+	{ // Continuation rune that is also an escape rune.
+		p.Escape = []rune{'\\'}
+		parsed, err := p.Parse("a = one \\\n  two \\\\\nb = C:\\\\temp\\\\\n")
+		chk.NoError(err)
+		chk.Equal("one two \\", parsed[""].Last["a"].Last)
+		chk.Equal("C:\\temp\\", parsed[""].Last["b"].Last)
+	}
+}
+
+func TestParserInlineComment(t *testing.T) {
+	chk := assert.New(t)
+	//
+	p := parser.DefaultParser
+	p.InlineComment = []rune{'#', ';'}
+	//
+	{
+		parsed, err := p.Parse(`
+		port = 8080  # http port
+		host = localhost	; tab before comment
+		color =#fff
+		url = http://example.com/#anchor
+		empty = # nothing here
+		quoted = "a # b" # comment after quotes
+		single = 'a ; b'
+	[ section ] # comment after section
+		key = value;not a comment
+	`)
+		chk.NoError(err)
+		global := parsed[""].Last
+		chk.Equal("8080", global["port"].Last)
+		chk.Equal("localhost", global["host"].Last)
+		chk.Equal("#fff", global["color"].Last)
+		chk.Equal("http://example.com/#anchor", global["url"].Last)
+		chk.Equal("", global["empty"].Last)
+		chk.Equal("a # b", global["quoted"].Last)
+		chk.Equal("a ; b", global["single"].Last)
+		chk.Equal("value;not a comment", parsed["section"].Last["key"].Last)
+	}
+	{ // Escaped comment runes and continuation.
+		p.Escape = []rune{'\\'}
+		p.Continue = []rune{'\\'}
+		parsed, err := p.Parse("a = b \\# c\nd = e \\\n  # f\ng = h\\  # i\nj = k\\ # l\n")
+		chk.NoError(err)
+		global := parsed[""].Last
+		chk.Equal("b # c", global["a"].Last)
+		chk.Equal("e", global["d"].Last)
+		chk.Equal("h ", global["g"].Last)
+		chk.Equal("k # l", global["j"].Last)
+	}
+	{ // Without inline comment runes the comment is part of the value.
+		parsed, err := parser.DefaultParser.Parse("port = 8080  # http port\n")
+		chk.NoError(err)
+		chk.Equal("8080  # http port", parsed[""].Last["port"].Last)
+	}
+}
+
+func TestParserCommentRunes(t *testing.T) {
+	chk := assert.New(t)
+	//
+	p := parser.DefaultParser
+	p.Comment = []rune{'#', ';'}
+	//
+	{
+		parsed, err := p.Parse(`
+		# a comment
+		; another comment
+		key = value
+	`)
+		chk.NoError(err)
+		chk.Equal("value", parsed[""].Last["key"].Last)
+	}
+	{
+		_, err := p.Parse("key = value\n  -key = value\n")
+		var syntaxErr *parser.SyntaxError
+		if chk.True(stderrors.As(err, &syntaxErr)) {
+			chk.Equal(parser.Position{Line: 2, Column: 3}, syntaxErr.Position)
+			chk.Equal("  -key = value", syntaxErr.Excerpt)
+		}
+	}
+	{ // DefaultParser is lenient.
+		parsed, err := parser.DefaultParser.Parse("-key = value\n^ also a comment\n")
+		chk.NoError(err)
+		chk.Equal(0, len(parsed[""].Last))
+	}
+}
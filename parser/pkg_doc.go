@@ -21,7 +21,63 @@
 //	}
 //
 // The given runes should be mutually exclusive sets when creating a Parser; the behavior for disregarding
-// this rule is undefined.
+// this rule is undefined.  The exception is RawQuote, which should be a subset of Quote.
+//
+// Escape Sequences
+//
+// DefaultParser does not process escape sequences.  Set Runes.Escape to allow quote runes, control characters,
+// and unicode code points to be escaped within values:
+//	myParser := parser.DefaultParser
+//	myParser.Escape = []rune{'\\'}
+//	myParser.RawQuote = []rune{'`'} // Backtick quoted values are not unescaped.
+//
+// See Parser for the supported escape sequences.
+//
+// Line Continuation
+//
+// DefaultParser does not continue values onto following lines.  Set Runes.Continue to allow long unquoted
+// values to span lines:
+//	myParser := parser.DefaultParser
+//	myParser.Continue = []rune{'\\'}
+//
+// See Parser for details.
+//
+// Comment Lines
+//
+// DefaultParser treats any punctuation at the beginning of a line as a comment unless it opens a section.  Set
+// Runes.Comment so only specific runes begin comments and any other punctuation is a syntax error:
+//	myParser := parser.DefaultParser
+//	myParser.Comment = []rune{'#', ';'}
+//
+// Inline Comments
+//
+// DefaultParser treats the remainder of a value line as part of the value.  Set Runes.InlineComment to allow
+// comments after values:
+//	myParser := parser.DefaultParser
+//	myParser.InlineComment = []rune{'#', ';'}
+//
+// Inline comments must be preceded by whitespace and are not recognized within quotations:
+//	port = 8080 # http port       -> 8080
+//	color =#fff                   -> #fff
+//	message = "hello # world"     -> hello # world
+//
+// Include Directives
+//
+// Set Parser.Include to the name of a key that includes other files:
+//	myParser := parser.DefaultParser
+//	myParser.Include = "include"
+//
+// See Parser for details.  Files parsed with Parser.ParseFS include files from the same fs.FS; include patterns
+// beginning with / are relative to the root of the fs.FS.
+//
+// Interpolation
+//
+// Set Parser.Interpolate to expand ${key}, ${section.key}, and ${env:NAME} references within values once
+// parsing completes:
+//	myParser := parser.DefaultParser
+//	myParser.Interpolate = true
+//
+// See Parsed.Interpolate for details.
 //
 // The Parsed Type
 //
@@ -57,6 +113,24 @@
 //	Slice[1] is the section where listen = example.com
 //	Last is the same section as Slice[1]
 //
+// Subsections
+//
+// Set Parser.Subsections to name subsections within section headers:
+//	myParser := parser.DefaultParser
+//	myParser.Subsections = parser.SubsectionsQuoted // [remote "origin"]
+//	myParser.Subsections = parser.SubsectionsSpace  // [remote origin] or [remote "origin"]
+//
+// Sections are stored under the section name and SectionBlock.Subsections[k] is the subsection of Slice[k]:
+//	[remote "origin"]
+//	url = git@example.com:origin
+//
+//	[remote "upstream"]
+//	url = git@example.com:upstream
+//
+// Creates a SectionBlock named remote where:
+//	Subsections[0] = origin
+//	Subsections[1] = upstream
+//
 // Section and its Values
 //
 // The Section and Value types repeat some of concepts encountered already.  A Section is a map[string]Value or
@@ -77,6 +151,25 @@
 //	Section["listen"].Slice[1] = example.com
 //	Section["listen"].Last is the same as Section["listen"].Slice[1]
 //
+// Positions
+//
+// SectionBlock and Value also record where their data came from.  SectionBlock.Positions[k] is the position
+// of the section header for Slice[k] and Value.Positions[k] is the position of the key for Slice[k].  Positions
+// contain the file name when parsing with ParseFile.
+//
+// Documents
+//
+// Parsed discards comments, blank lines, and quotations.  Use Parser.ParseDocument to edit configuration while
+// preserving them; a Document that is not edited is written back byte-for-byte identical to its input:
+//	doc, err := parser.DefaultParser.ParseDocument(s)
+//	err = doc.Set("", "version", "1.0.1")
+//	err = doc.Append("server", "listen", ":8080")
+//	doc.Delete("database", "password")
+//	_, err = doc.RenameSection("db", "database")
+//	_, err = doc.WriteTo(w)
+//
+// Changed values keep their quotation when possible and are otherwise quoted as needed.
+//
 // The End Result
 //
 // The end result is a convenient configuration syntax that allows repeated sections and repeated key=values
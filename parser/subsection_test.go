@@ -0,0 +1,130 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestParserSubsections(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+global = value
+[remote "origin"]
+url = git@example.com:origin
+[remote]
+url = none
+[remote 'upstream'  ]
+url = git@example.com:upstream
+[remote "with ] and spaces"]
+url = odd
+[remote extra]
+url = extra
+`
+	{
+		// SubsectionsNone is the default; quoted subsections are errors.
+		_, err := parser.DefaultParser.Parse(s)
+		chk.Error(err)
+	}
+	{
+		p := parser.DefaultParser
+		p.Subsections = parser.SubsectionsQuoted
+		parsed, err := p.Parse(s)
+		chk.NoError(err)
+		chk.Equal([]string{""}, parsed[""].Subsections)
+		remote := parsed["remote"]
+		if chk.NotNil(remote) && chk.Len(remote.Slice, 4) {
+			chk.Equal([]string{"origin", "", "upstream", "with ] and spaces"}, remote.Subsections)
+			chk.Equal("git@example.com:origin", remote.Slice[0]["url"].Last)
+			chk.Equal("none", remote.Slice[1]["url"].Last)
+			chk.Equal("odd", remote.Slice[3]["url"].Last)
+			chk.Equal(3, remote.Positions[0].Line)
+		}
+		// Without SubsectionsSpace whitespace is part of the section name.
+		if chk.NotNil(parsed["remote extra"]) {
+			chk.Equal([]string{""}, parsed["remote extra"].Subsections)
+		}
+		// Documents preserve the section headers.
+		doc, err := p.ParseDocument(s)
+		chk.NoError(err)
+		if chk.NotNil(doc) {
+			chk.Equal(s, doc.String())
+			chk.Len(doc.Sections("remote"), 4)
+		}
+	}
+	{
+		p := parser.DefaultParser
+		p.Subsections = parser.SubsectionsSpace
+		parsed, err := p.Parse(s)
+		chk.NoError(err)
+		remote := parsed["remote"]
+		if chk.NotNil(remote) && chk.Len(remote.Slice, 5) {
+			chk.Equal([]string{"origin", "", "upstream", "with ] and spaces", "extra"}, remote.Subsections)
+			chk.Equal("extra", remote.Slice[4]["url"].Last)
+			chk.Equal("extra", remote.Subsection(4))
+			chk.Equal("", remote.Subsection(5))
+		}
+		chk.Nil(parsed["remote extra"])
+		//
+		parsed, err = p.Parse("[server   web  01]\nport = 80\n")
+		chk.NoError(err)
+		if chk.NotNil(parsed["server"]) {
+			chk.Equal("web  01", parsed["server"].Subsection(0))
+		}
+	}
+	{
+		// Escaped quotes within quoted subsections.
+		p := parser.DefaultParser
+		p.Subsections = parser.SubsectionsQuoted
+		p.Escape = []rune{'\\'}
+		parsed, err := p.Parse(`[remote "a \"b\""]` + "\nurl = x\n")
+		chk.NoError(err)
+		if chk.NotNil(parsed["remote"]) {
+			chk.Equal(`a "b"`, parsed["remote"].Subsection(0))
+		}
+	}
+	{
+		// Without a rule Subsections is nil.
+		parsed, err := parser.DefaultParser.Parse("[remote]\nurl = x\n")
+		chk.NoError(err)
+		chk.Nil(parsed["remote"].Subsections)
+		chk.Equal("", parsed["remote"].Subsection(0))
+	}
+}
+
+func TestParserSubsectionsErrors(t *testing.T) {
+	chk := assert.New(t)
+	//
+	p := parser.DefaultParser
+	p.Subsections = parser.SubsectionsQuoted
+	type test struct {
+		s       string
+		line    int
+		column  int
+		message string
+		excerpt string
+	}
+	tests := []test{
+		{"[remote \"origin\nurl = x\n", 1, 9, "Parsing section name; unterminated quotation \"", ""},
+		{"[remote \"origin\" x]\n", 1, 18, `Parsing section name; unexpected token= "x"`, "[remote \"origin\" x]"},
+		{"[remote \"a\"\nk=v\n", 1, 12, `Parsing section name; unexpected token= "\n"`, "[remote \"a\""},
+		{"[remote \"origin\"", 1, 17, "Unexpected EOF while parsing Section", ""},
+		{"[\"origin\"]\n", 1, 10, "Parsing section name; unexpected token= ]", ""},
+	}
+	for _, test := range tests {
+		_, err := p.Parse(test.s)
+		var syntax *parser.SyntaxError
+		if chk.True(errors.As(err, &syntax), test.s) {
+			chk.Equal(test.line, syntax.Position.Line, test.s)
+			chk.Equal(test.column, syntax.Position.Column, test.s)
+			chk.Equal(test.message, syntax.Message, test.s)
+			if test.excerpt != "" {
+				chk.Equal(test.excerpt, syntax.Excerpt, test.s)
+			}
+		}
+	}
+}
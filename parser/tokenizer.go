@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Token describes a token type.
@@ -52,6 +54,17 @@ type Tokenizer interface {
 	Rewind()
 }
 
+// PositionTokenizer is a Tokenizer that reports where its tokens are; the Tokenizers returned from NewTokenizer
+// and NewReaderTokenizer implement it.  Syntax errors are positioned at 0:0 for Tokenizers that do not.
+type PositionTokenizer interface {
+	Tokenizer
+	// Position returns the position of the token that would be returned by Peek().
+	Position() Position
+	// Excerpt returns the full line of input containing the token that would be returned by Peek();
+	// the line terminator is not included.
+	Excerpt() string
+}
+
 // tokenizer reads a string into tokens.
 type tokenizer struct {
 	// String to tokenize.
@@ -60,19 +73,56 @@ type tokenizer struct {
 	max int
 	// Current index into s.
 	n int
-	// The number of runes peeked at; if 0 then nothing yet peeked.
+	// The number of bytes peeked at; if 0 then nothing yet peeked.
 	peek int
 	// The peeked token's type.
 	typ Token
+	// Line and column of n as well as the index into s where the line begins.
+	line      int
+	column    int
+	lineStart int
 	// A stack of n, peek, and typ to allow for forward scanning but rewinding.
 	rewindN    int
 	rewindPeek int
 	rewindTyp  Token
+	// A stack of line, column, and lineStart to allow for rewinding.
+	rewindLine      int
+	rewindColumn    int
+	rewindLineStart int
+}
+
+// byteOrderMark is the UTF-8 encoding of U+FEFF; it is skipped when it begins the input.
+const byteOrderMark = "\uFEFF"
+
+// isAlphaNum returns true if r is part of a TokenAlphaNum; combining marks are included so decomposed
+// characters remain a single token.
+func isAlphaNum(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// lineBreaks returns the number of line breaks in a TokenNewline; a \r\n pair is a single line break
+// and a lone \r or \n is also a line break.
+func lineBreaks(s string) int {
+	n := 0
+	for k, size := 0, len(s); k < size; k++ {
+		if s[k] == '\n' || k+1 == size || s[k+1] != '\n' {
+			n++
+		}
+	}
+	return n
 }
 
-// NewTokenizer creates a new Tokenizer type.
+// NewTokenizer creates a new Tokenizer type.  The input is decoded as UTF-8 and a leading byte order
+// mark is skipped.
 func NewTokenizer(s string) Tokenizer {
-	rv := &tokenizer{s, len(s), 0, 0, TokenNone, 0, 0, TokenNone}
+	start := 0
+	if strings.HasPrefix(s, byteOrderMark) {
+		start = len(byteOrderMark)
+	}
+	rv := &tokenizer{
+		s: s, max: len(s), n: start, typ: TokenNone, rewindN: start, rewindTyp: TokenNone,
+		line: 1, column: 1, lineStart: start, rewindLine: 1, rewindColumn: 1, rewindLineStart: start,
+	}
 	return rv
 }
 
@@ -85,6 +135,7 @@ func (me *tokenizer) EOF() bool {
 // this position.  Use this to make consecutive calls to Peek() or Next() for look-ahead past a single token.
 func (me *tokenizer) Memory() {
 	me.rewindN, me.rewindPeek, me.rewindTyp = me.n, me.peek, me.typ
+	me.rewindLine, me.rewindColumn, me.rewindLineStart = me.line, me.column, me.lineStart
 }
 
 // Peek returns the next token and its type without advancing the internal counters; an empty string signals
@@ -96,15 +147,17 @@ func (me *tokenizer) Peek() (string, Token) {
 		return me.s[me.n : me.n+me.peek], me.typ
 	}
 	//
+	r, size := utf8.DecodeRuneInString(me.s[me.n:])
 	next := func() (rune, bool) {
-		me.peek++
+		me.peek = me.peek + size
 		if me.n+me.peek >= me.max {
 			return ' ', false
 		}
-		return rune(me.s[me.n+me.peek]), true
+		r, size = utf8.DecodeRuneInString(me.s[me.n+me.peek:])
+		return r, true
 	}
 	//
-	r, ok := rune(me.s[me.n]), true
+	ok := true
 	if r == ' ' || r == '\t' {
 		// Consumes spaces and tabs.
 		for ; ok && (r == ' ' || r == '\t'); r, ok = next() {
@@ -115,12 +168,12 @@ func (me *tokenizer) Peek() (string, Token) {
 		for ; ok && (r == '\r' || r == '\n'); r, ok = next() {
 		}
 		me.typ = TokenNewline
-	} else if unicode.IsDigit(r) || unicode.IsLetter(r) {
-		for ; ok && (unicode.IsDigit(r) || unicode.IsLetter(r)); r, ok = next() {
+	} else if isAlphaNum(r) {
+		for ; ok && isAlphaNum(r); r, ok = next() {
 		}
 		me.typ = TokenAlphaNum
 	} else {
-		me.peek = 1
+		me.peek = size
 		me.typ = TokenPunct
 	}
 	//
@@ -130,15 +183,36 @@ func (me *tokenizer) Peek() (string, Token) {
 // Next returns the token and its type that would be returned by Peek() but then advances internal
 // counters to the next possible token.
 func (me *tokenizer) Next() (string, Token) {
-	defer func() {
-		me.n = me.n + me.peek
-		me.peek = 0
-	}()
-	return me.Peek()
+	str, typ := me.Peek()
+	if typ == TokenNewline {
+		me.line = me.line + lineBreaks(str)
+		me.column, me.lineStart = 1, me.n+me.peek
+	} else {
+		me.column = me.column + utf8.RuneCountInString(str)
+	}
+	me.n = me.n + me.peek
+	me.peek = 0
+	return str, typ
 }
 
 // Rewind resets the Tokenizer to the position recorded by calling Memory() or to the beginning
 // of the string if Memory was never called.
 func (me *tokenizer) Rewind() {
 	me.n, me.peek, me.typ = me.rewindN, me.rewindPeek, me.rewindTyp
+	me.line, me.column, me.lineStart = me.rewindLine, me.rewindColumn, me.rewindLineStart
+}
+
+// Position returns the position of the token that would be returned by Peek().
+func (me *tokenizer) Position() Position {
+	return Position{Line: me.line, Column: me.column}
+}
+
+// Excerpt returns the full line of input containing the token that would be returned by Peek();
+// the line terminator is not included.
+func (me *tokenizer) Excerpt() string {
+	line := me.s[me.lineStart:]
+	if n := strings.IndexAny(line, "\r\n"); n != -1 {
+		line = line[:n]
+	}
+	return line
 }
@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"io"
+	"strings"
+)
+
+// token is a token returned from readerTokenizer along with the position and line where it began.
+type token struct {
+	str     string
+	typ     Token
+	pos     Position
+	excerpt string
+}
+
+// readerTokenizer reads tokens from an io.RuneReader one line at a time.
+type readerTokenizer struct {
+	// Reader to tokenize.
+	r io.RuneReader
+	// The first error returned from r; io.EOF when r is exhausted.
+	err error
+	// A rune read from r while looking for \r\n but that belongs to the next line.
+	pending    rune
+	hasPending bool
+	// True until the first rune is read; used to skip a leading byte order mark.
+	first bool
+	// The current line including its line terminator, the index of the next rune to tokenize, and the
+	// line without its terminator.
+	line    []rune
+	n       int
+	excerpt string
+	// Position of line[n].
+	pos Position
+	// The peeked token; valid when peeked is true.
+	peek   token
+	peeked bool
+	// Tokens consumed since Memory() was called when remember is true.
+	remember bool
+	memory   []token
+	// Tokens restored by Rewind() that are returned before reading from r.
+	replay []token
+}
+
+// NewReaderTokenizer creates a Tokenizer that reads from r as tokens are requested rather than reading
+// all of r up front; only the current line and any tokens retained by Memory() are held in memory.  A leading
+// byte order mark is skipped.
+//
+// Unlike the Tokenizer returned from NewTokenizer a call to Rewind() is ignored if Memory() was never called.
+//
+// An error returned from r other than io.EOF ends the input; the returned Tokenizer has an Err() error
+// method to retrieve it.
+func NewReaderTokenizer(r io.RuneReader) Tokenizer {
+	rv := &readerTokenizer{r: r, first: true, pos: Position{Line: 1, Column: 1}}
+	return rv
+}
+
+// Err returns the error, if any, that ended the input early.
+func (me *readerTokenizer) Err() error {
+	if me.err == io.EOF {
+		return nil
+	}
+	return me.err
+}
+
+// readRune returns the next rune from r.
+func (me *readerTokenizer) readRune() (rune, bool) {
+	if me.hasPending {
+		me.hasPending = false
+		return me.pending, true
+	} else if me.err != nil {
+		return 0, false
+	}
+	r, _, err := me.r.ReadRune()
+	if err == nil && me.first && r == '\uFEFF' {
+		r, _, err = me.r.ReadRune()
+	}
+	me.first = false
+	if err != nil {
+		me.err = err
+		return 0, false
+	}
+	return r, true
+}
+
+// readLine replaces the current line with the next line from r and returns false if there are no more lines.
+func (me *readerTokenizer) readLine() bool {
+	me.line, me.n = me.line[:0], 0
+	for r, ok := me.readRune(); ok; r, ok = me.readRune() {
+		me.line = append(me.line, r)
+		if r == '\n' {
+			break
+		} else if r == '\r' {
+			if r, ok = me.readRune(); ok && r == '\n' {
+				me.line = append(me.line, r)
+			} else if ok {
+				me.pending, me.hasPending = r, true
+			}
+			break
+		}
+	}
+	if len(me.line) > 0 || me.pos.Column == 1 {
+		// The excerpt is kept when the final line has no terminator.
+		me.excerpt = strings.TrimRight(string(me.line), "\r\n")
+	}
+	return len(me.line) > 0
+}
+
+// EOF returns true when the tokenizer has no more tokens to return.
+func (me *readerTokenizer) EOF() bool {
+	_, typ := me.Peek()
+	return typ == TokenNone
+}
+
+// Memory records the current Tokenizer position and a call to Rewid() will reset the Tokenizer to
+// this position.  Use this to make consecutive calls to Peek() or Next() for look-ahead past a single token.
+func (me *readerTokenizer) Memory() {
+	me.remember, me.memory = true, nil
+	if me.peeked {
+		// The peeked token is returned again after Rewind() and must be remembered.
+		me.replay, me.peeked = append([]token{me.peek}, me.replay...), false
+	}
+}
+
+// Peek returns the next token and its type without advancing the internal counters; an empty string signals
+// the end of the Tokenizer's input.
+func (me *readerTokenizer) Peek() (string, Token) {
+	if me.peeked {
+		return me.peek.str, me.peek.typ
+	} else if len(me.replay) > 0 {
+		me.peek, me.replay, me.peeked = me.replay[0], me.replay[1:], true
+		return me.peek.str, me.peek.typ
+	}
+	//
+	me.peeked = true
+	if me.n >= len(me.line) && !me.readLine() {
+		me.peek = token{typ: TokenNone, pos: me.pos, excerpt: me.excerpt}
+		return "", TokenNone
+	}
+	me.peek = token{pos: me.pos, excerpt: me.excerpt}
+	//
+	start, size := me.n, len(me.line)
+	r := me.line[me.n]
+	if r == ' ' || r == '\t' {
+		// Consumes spaces and tabs.
+		for ; me.n < size && (me.line[me.n] == ' ' || me.line[me.n] == '\t'); me.n++ {
+		}
+		me.peek.typ = TokenWhiteSpace
+	} else if r == '\r' || r == '\n' {
+		// Consumes newlines; lines are split after their terminator so the remainder of this line is
+		// the terminator and consecutive empty lines are joined into this token.
+		s := &strings.Builder{}
+		for {
+			s.WriteString(string(me.line[me.n:]))
+			me.n = len(me.line)
+			me.pos.Line, me.pos.Column = me.pos.Line+1, 1
+			if !me.readLine() || (me.line[0] != '\r' && me.line[0] != '\n') {
+				break
+			}
+		}
+		me.peek.str, me.peek.typ = s.String(), TokenNewline
+		return me.peek.str, me.peek.typ
+	} else if isAlphaNum(r) {
+		for ; me.n < size && isAlphaNum(me.line[me.n]); me.n++ {
+		}
+		me.peek.typ = TokenAlphaNum
+	} else {
+		me.n++
+		me.peek.typ = TokenPunct
+	}
+	me.peek.str = string(me.line[start:me.n])
+	me.pos.Column = me.pos.Column + me.n - start
+	//
+	return me.peek.str, me.peek.typ
+}
+
+// Next returns the token and its type that would be returned by Peek() but then advances internal
+// counters to the next possible token.
+func (me *readerTokenizer) Next() (string, Token) {
+	str, typ := me.Peek()
+	if typ != TokenNone {
+		me.peeked = false
+		if me.remember {
+			me.memory = append(me.memory, me.peek)
+		}
+	}
+	return str, typ
+}
+
+// Rewind resets the Tokenizer to the position recorded by calling Memory().
+func (me *readerTokenizer) Rewind() {
+	replay := me.memory
+	if me.peeked {
+		replay = append(replay, me.peek)
+	}
+	me.replay, me.memory, me.peeked = append(replay, me.replay...), nil, false
+}
+
+// Position returns the position of the token that would be returned by Peek().
+func (me *readerTokenizer) Position() Position {
+	me.Peek()
+	return me.peek.pos
+}
+
+// Excerpt returns the full line of input containing the token that would be returned by Peek();
+// the line terminator is not included.
+func (me *readerTokenizer) Excerpt() string {
+	me.Peek()
+	return me.peek.excerpt
+}
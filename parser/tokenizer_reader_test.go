@@ -0,0 +1,120 @@
+package parser_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+func TestReaderTokenizerMatchesTokenizer(t *testing.T) {
+	chk := assert.New(t)
+	//
+	inputs := []string{
+		"",
+		"asdf 1234 ;\t\t\r\nfinale;",
+		"key = value\r\n\n  [main]\rx",
+		"\n\n\r\n\r\rlast\n",
+		"\uFEFFclé ≈ «naïve» café́日本語\n\n",
+		`
+[main]
+# This is a comment.
+value = true
+
+[other]
+
+`,
+	}
+	for _, input := range inputs {
+		expect := parser.NewTokenizer(input).(parser.PositionTokenizer)
+		got := parser.NewReaderTokenizer(bufio.NewReader(iotest.OneByteReader(strings.NewReader(input)))).(parser.PositionTokenizer)
+		for !expect.EOF() {
+			chk.Equal(false, got.EOF(), input)
+			chk.Equal(expect.Position(), got.Position(), input)
+			chk.Equal(expect.Excerpt(), got.Excerpt(), input)
+			expectTok, expectTyp := expect.Next()
+			gotTok, gotTyp := got.Next()
+			chk.Equal(expectTok, gotTok, input)
+			chk.Equal(expectTyp, gotTyp, input)
+		}
+		chk.Equal(true, got.EOF(), input)
+		chk.Equal(expect.Position(), got.Position(), input)
+		chk.Equal(expect.Excerpt(), got.Excerpt(), input)
+		tok, typ := got.Next()
+		chk.Equal("", tok)
+		chk.Equal(parser.TokenNone, typ)
+	}
+}
+
+func TestReaderTokenizerMemoryRewind(t *testing.T) {
+	chk := assert.New(t)
+	//
+	tokenizer := parser.NewReaderTokenizer(strings.NewReader("hello = world\r\n[main")).(parser.PositionTokenizer)
+	//
+	tokenizer.Rewind() // Ignored without a call to Memory.
+	tok, _ := tokenizer.Next()
+	chk.Equal("hello", tok)
+	tokenizer.Rewind()
+	tok, _ = tokenizer.Peek()
+	chk.Equal(" ", tok)
+	//
+	tokenizer.Memory() // Memory after Peek() retains the peeked token.
+	for _, expect := range []string{" ", "=", " ", "world"} {
+		tok, _ = tokenizer.Next()
+		chk.Equal(expect, tok)
+	}
+	tokenizer.Peek()
+	tokenizer.Rewind()
+	chk.Equal(parser.Position{Line: 1, Column: 6}, tokenizer.Position())
+	for _, expect := range []string{" ", "=", " ", "world", "\r\n"} {
+		tok, _ = tokenizer.Next()
+		chk.Equal(expect, tok)
+	}
+	//
+	tokenizer.Memory()
+	for _, expect := range []string{"[", "main", ""} {
+		tok, _ = tokenizer.Next()
+		chk.Equal(expect, tok)
+	}
+	chk.Equal(true, tokenizer.EOF())
+	tokenizer.Rewind()
+	chk.Equal(false, tokenizer.EOF())
+	chk.Equal("[main", tokenizer.Excerpt())
+	for _, expect := range []string{"[", "main", ""} {
+		tok, _ = tokenizer.Next()
+		chk.Equal(expect, tok)
+	}
+}
+
+func TestReaderTokenizerErr(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type errTokenizer interface {
+		Err() error
+	}
+	{
+		tokenizer := parser.NewReaderTokenizer(strings.NewReader("a b"))
+		for !tokenizer.EOF() {
+			tokenizer.Next()
+		}
+		chk.NoError(tokenizer.(errTokenizer).Err())
+	}
+	{
+		r := bufio.NewReader(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("ab"))))
+		tokenizer := parser.NewReaderTokenizer(r)
+		tok, _ := tokenizer.Next()
+		chk.Equal("a", tok)
+		chk.Equal(true, tokenizer.EOF())
+		chk.Error(tokenizer.(errTokenizer).Err())
+	}
+	{
+		tokenizer := parser.NewReaderTokenizer(bufio.NewReader(iotest.ErrReader(errors.Errorf("kaboom"))))
+		chk.Equal(true, tokenizer.EOF())
+		chk.Error(tokenizer.(errTokenizer).Err())
+	}
+}
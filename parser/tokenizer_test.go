@@ -276,3 +276,100 @@ func TestTokenStrings(t *testing.T) {
 	chk.Equal("Whitespace", parser.TokenWhiteSpace.String())
 	chk.Equal(true, strings.HasPrefix(parser.Token(-10).String(), "Unknown"))
 }
+
+func TestTokenizerPosition(t *testing.T) {
+	chk := assert.New(t)
+	//
+	str := "key = value\r\n\n  [main]\rx"
+	tokenizer := parser.NewTokenizer(str).(parser.PositionTokenizer)
+	//
+	type Expect struct {
+		Token   string
+		Line    int
+		Column  int
+		Excerpt string
+	}
+	expect := []Expect{
+		{"key", 1, 1, "key = value"},
+		{" ", 1, 4, "key = value"},
+		{"=", 1, 5, "key = value"},
+		{" ", 1, 6, "key = value"},
+		{"value", 1, 7, "key = value"},
+		{"\r\n\n", 1, 12, "key = value"},
+		{"  ", 3, 1, "  [main]"},
+		{"[", 3, 3, "  [main]"},
+		{"main", 3, 4, "  [main]"},
+		{"]", 3, 8, "  [main]"},
+		{"\r", 3, 9, "  [main]"},
+		{"x", 4, 1, "x"},
+	}
+	for k, e := range expect {
+		if k == 7 {
+			tokenizer.Memory()
+		}
+		pos := tokenizer.Position()
+		chk.Equal(e.Line, pos.Line, e.Token)
+		chk.Equal(e.Column, pos.Column, e.Token)
+		chk.Equal(e.Excerpt, tokenizer.Excerpt(), e.Token)
+		tok, _ := tokenizer.Next()
+		chk.Equal(e.Token, tok)
+	}
+	chk.Equal(parser.Position{Line: 4, Column: 2}, tokenizer.Position())
+	//
+	tokenizer.Rewind()
+	chk.Equal(parser.Position{Line: 3, Column: 3}, tokenizer.Position())
+	chk.Equal("  [main]", tokenizer.Excerpt())
+}
+
+func TestTokenizerUTF8(t *testing.T) {
+	chk := assert.New(t)
+	//
+	// The é in "café" is decomposed into e and a combining acute accent.
+	str := "\uFEFFclé ≈ «naïve» café́日本語"
+	tokenizer := parser.NewTokenizer(str).(parser.PositionTokenizer)
+	//
+	type Expect struct {
+		Token  string
+		Type   parser.Token
+		Column int
+	}
+	expect := []Expect{
+		{"clé", parser.TokenAlphaNum, 1},
+		{" ", parser.TokenWhiteSpace, 4},
+		{"≈", parser.TokenPunct, 5},
+		{" ", parser.TokenWhiteSpace, 6},
+		{"«", parser.TokenPunct, 7},
+		{"naïve", parser.TokenAlphaNum, 8},
+		{"»", parser.TokenPunct, 13},
+		{" ", parser.TokenWhiteSpace, 14},
+		{"café́日本語", parser.TokenAlphaNum, 15},
+	}
+	for _, e := range expect {
+		chk.Equal(e.Column, tokenizer.Position().Column, e.Token)
+		tok, typ := tokenizer.Next()
+		chk.Equal(e.Token, tok)
+		chk.Equal(e.Type, typ, e.Token)
+	}
+	chk.Equal(true, tokenizer.EOF())
+	//
+	tokenizer.Rewind()
+	chk.Equal("clé ≈ «naïve» café́日本語", tokenizer.Excerpt())
+	tok, _ := tokenizer.Next()
+	chk.Equal("clé", tok)
+}
+
+func TestTokenizerInvalidUTF8(t *testing.T) {
+	chk := assert.New(t)
+	//
+	tokenizer := parser.NewTokenizer("a\xffb")
+	tok, typ := tokenizer.Next()
+	chk.Equal("a", tok)
+	chk.Equal(parser.TokenAlphaNum, typ)
+	tok, typ = tokenizer.Next()
+	chk.Equal("\xff", tok)
+	chk.Equal(parser.TokenPunct, typ)
+	tok, typ = tokenizer.Next()
+	chk.Equal("b", tok)
+	chk.Equal(parser.TokenAlphaNum, typ)
+	chk.Equal(true, tokenizer.EOF())
+}
@@ -1,15 +1,36 @@
 package parser
 
+import "fmt"
+
 // IsAssign is a function that returns true if the given rune is used to assign a value to a key.
 type IsAssign func(rune) bool
 
 // IsQuote is a function that returns true if the given rune is used to quote strings.
 type IsQuote func(rune) bool
 
+// Position describes a location within configuration input; Line and Column begin at 1 and Column
+// counts runes.  File is empty when the input did not come from a named file.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String returns the position as file:line:column or line:column when File is empty.
+func (me Position) String() string {
+	if me.File == "" {
+		return fmt.Sprintf("%v:%v", me.Line, me.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", me.File, me.Line, me.Column)
+}
+
 // Value is the value in a key=value configuration section; values can be singular or slices.
+//
+// Positions[k] is the position of the key that defined Slice[k].
 type Value struct {
-	Last  string
-	Slice []string
+	Last      string
+	Slice     []string
+	Positions []Position
 }
 
 // Section is the key=value store of a configuration section.
@@ -26,9 +47,25 @@ func (me Section) Map() map[string][]string {
 
 // SectionBlock contains a slice of all sections that had the same name as well as the last section
 // that had the name.
+//
+// Positions[k] is the position of the section header that began Slice[k]; the global section is
+// positioned at the beginning of the input.
+//
+// Subsections[k] is the subsection named in the section header that began Slice[k] or empty when it did not
+// name one; Subsections is nil unless the Parser's SubsectionRule is set.
 type SectionBlock struct {
-	Last  Section
-	Slice []Section
+	Last        Section
+	Slice       []Section
+	Positions   []Position
+	Subsections []string
+}
+
+// Subsection returns Subsections[k] if it exists.
+func (me *SectionBlock) Subsection(k int) string {
+	if me == nil || k < 0 || k >= len(me.Subsections) {
+		return ""
+	}
+	return me.Subsections[k]
 }
 
 // Map returns the section block as a []map[string][]string.
@@ -42,3 +42,9 @@ func TestMap(t *testing.T) {
 		chk.Equal("BB", second["b"][0])
 	}
 }
+
+func TestPositionString(t *testing.T) {
+	chk := assert.New(t)
+	chk.Equal("3:14", parser.Position{Line: 3, Column: 14}.String())
+	chk.Equal("app.conf:3:14", parser.Position{File: "app.conf", Line: 3, Column: 14}.String())
+}
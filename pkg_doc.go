@@ -50,6 +50,204 @@
 //
 // Use Conf.Fill() and Conf.FillByTag() to populate parsed configuration into your structures.  Examples are provided below.
 //
+// Maps
+//
+// A map[string]T field where T is a scalar or slice of scalars receives every key of the section with the
+// field's name.  A map[string]T field where T is a struct receives a struct for each section whose name begins
+// with the field's name followed by whitespace; the remainder of the section name is the map key:
+//	[headers]
+//	X-Frame-Options = DENY
+//
+//	[backend web]
+//	host = 10.0.0.1
+//
+//	[backend api]
+//	host = 10.0.0.2
+//
+//	type Config struct {
+//		Headers  map[string]string  `conf:"headers"`
+//		Backends map[string]Backend `conf:"backend"` // Keys are web and api.
+//	}
+//
+// A map field can not share its name with a struct or slice of structs field in the same struct.
+//
+// Subsections
+//
+// Set the parser's Subsections rule to name subsections in section headers as in [remote "origin"] or
+// [remote origin].  Sections are stored under the section name and a field with the name option receives the
+// subsection; maps of structs are keyed by subsection:
+//	loader := conf.Loader{Parser: parser.DefaultParser}
+//	loader.Parser.Subsections = parser.SubsectionsQuoted
+//
+//	[remote "origin"]
+//	url = git@example.com:app
+//
+//	type Remote struct {
+//		Name string `conf:",name"` // origin
+//		URL  string `conf:"url"`
+//	}
+//	type Config struct {
+//		Remotes []Remote          `conf:"remote"`
+//		// or
+//		ByName  map[string]Remote `conf:"remote"`
+//	}
+//
+// Loader.Load merges sections naming a subsection with the section naming the same subsection.  Set
+// Encoder.Subsections to write fields with the name option as quoted subsections; without it they return an
+// error.
+//
+// Marshal
+//
+// Use Marshal(), MarshalByTag(), or an Encoder to write a struct as configuration text that parser.DefaultParser
+// parses back into the same struct.  Global keys are written first followed by sections; slices become repeated
+// keys and slices of structs become repeated sections.  Maps are written in the form described under Maps with
+// map keys in sorted order:
+//	b, err := conf.MarshalByTag("conf", &cfg)
+//
+// Getters
+//
+// Read individual values without a struct; use "" for the global section.  When a section is repeated the last
+// one is used; use Conf.Sections() for every section with a name:
+//	name, ok := c.Get("", "name")
+//	port, err := c.GetInt("server", "port")
+//	for _, server := range c.Sections("server") {
+//		timeout, err := server.GetDuration("timeout")
+//	}
+//
+// GetInt and GetBool convert values the same way as Fill.  GetDuration differs; it parses values such as 5s with
+// time.ParseDuration while Fill only accepts an integer number of nanoseconds for a time.Duration field.  Missing
+// keys and values that can not be converted are returned as *ValueError.
+//
+// Default Values
+//
+// A default tag provides the value for a key that is missing from the configuration; a key that is present
+// but empty keeps its empty value.  Defaults for slices are split on commas:
+//	type T struct {
+//		Port  int      `conf:"port" default:"8080"`
+//		Hosts []string `conf:"hosts" default:"a,b"`
+//	}
+//
+// Defaults apply within every repeated section and to a missing section filled into a struct.  Use
+// FillOptions.DefaultTag and FillOptions.DefaultSeparator to change the tag and separator.
+//
+// Required Keys and Sections
+//
+// Add the required option to a struct tag to require a key or section; a required section filled into a slice
+// must appear at least once.  When filling by field name use a separate required:"true" tag:
+//	type T struct {
+//		Database struct {
+//			DSN string `conf:"dsn,required"`
+//		} `conf:"database,required"`
+//	}
+//
+// Keys provided by defaults or environment variables are not missing.  Every missing key and section is
+// returned in a single *RequiredError.
+//
+// Unused Keys and Sections
+//
+// FillOptions.Strict returns every key and section that is not consumed by the target as *UnusedError along
+// with where it was defined; typos such as listne = :80 are reported rather than ignored.  A repeated section
+// filled into a struct or map is replaced by the last one, so the earlier sections are reported as unused.
+// Conf.Unused() returns the same list without filling so it can be logged as warnings instead.
+//
+// Environment Variables
+//
+// Conf.FillWith() with FillOptions.Env overlays environment variables onto the configuration.  Variables are
+// named from the prefix, section, and key; letters are upper cased and other characters become underscores:
+//	c.FillWith(&cfg, conf.FillOptions{Tag: "conf", Env: true, EnvPrefix: "APP"})
+//
+//	APP_NAME=example            # global key: name
+//	APP_DATABASE_HOST=db        # key host in [database]
+//	APP_SERVER_1_LISTEN=:443    # key listen in the second [server] when filling a slice
+//
+// An env tag names the variable explicitly; within a repeated section the index is appended as in
+// DB_PASSWORD_1.  An env tag on a section field replaces the prefix and section name.  Variables for slices
+// are split on FillOptions.EnvSeparator.  Variables for the index following the last repeated section append
+// a new section.
+//
+// Loader
+//
+// File() and String() use parser.DefaultParser.  Use a Loader to enable optional parser features such as escape
+// sequences, include directives, or interpolation of ${key}, ${section.key}, and ${env:NAME} references:
+//	loader := conf.Loader{Parser: parser.DefaultParser}
+//	loader.Parser.Interpolate = true
+//	c, err := loader.File("app.conf")
+//
+// See the parser package for all of the available features.
+//
+// FS() reads configuration from an fs.FS such as an embed.FS or fstest.MapFS:
+//	//go:embed defaults.conf
+//	var defaults embed.FS
+//
+//	c, err := conf.FS(defaults, "defaults.conf")
+//
+// Multiple Sources
+//
+// Load() parses a list of sources in order and merges them; later sources replace keys from earlier sources and
+// sections with the same name are merged.  Optional files that do not exist are skipped:
+//	c, err := conf.Load(
+//		conf.FileSource("defaults.conf"),
+//		conf.OptionalFileSource("/etc/app/app.conf"),
+//		conf.StringSource(extra),
+//	)
+//
+// Set Loader.Merge to append repeated keys or to append or replace repeated sections instead.  When the Loader's
+// parser interpolates, references are expanded once after every source is merged so they can refer to keys from
+// any source and see the final value of overridden keys.
+//
+// Dir() and Glob() parse and merge every file in a directory or matching a pattern in lexical order; syntax
+// errors record the file that contains them.  Hidden files and editor backups such as file~ and file.swp are
+// skipped; set Loader.Skip to choose which files are skipped:
+//	c, err := conf.Dir("/etc/app/conf.d")
+//	c, err := conf.Load(conf.FileSource("/etc/app/app.conf"), conf.GlobSource("/etc/app/conf.d/*.conf"))
+//
+// Hot Reload
+//
+// A Watcher polls files and reloads them when their contents change.  Each reload fills a new struct and
+// publishes it atomically with the parsed configuration; failed reloads keep the last good configuration.  A
+// changed file is reloaded once it is unchanged between two polls so partially written files are not loaded:
+//	w := &conf.Watcher{
+//		Files:       []string{"/etc/app/app.conf"},
+//		New:         func() interface{} { return &Config{} },
+//		FillOptions: conf.FillOptions{Tag: "conf"},
+//		OnError:     func(err error) { log.Println(err) },
+//	}
+//	if err := w.Start(); err != nil {
+//		return err
+//	}
+//	defer w.Stop()
+//	cfg := w.Snapshot().Value.(*Config)
+//	for snapshot := range w.Subscribe() {
+//		cfg = snapshot.Value.(*Config)
+//	}
+//
+// Store
+//
+// A Store holds the current configuration for use by many goroutines without locking.  Replace fills and
+// validates a new configuration before storing it and calls back for keys that changed:
+//	store := &conf.Store{New: func() interface{} { return &Config{} }}
+//	store.OnChange("server", "listen", func(old, new []string) {
+//		// restart the listener
+//	})
+//	err := store.Replace(c)
+//	cfg := store.Value().(*Config)
+//
+// Diff
+//
+// Diff compares two configurations and returns the keys and sections that were added, removed, or modified.
+// Sections with the same name are compared by subsection when their headers name one and otherwise by position.
+// Changes.String() returns a unified-style report:
+//	fmt.Print(conf.Diff(old, new))
+//	@@ global @@
+//	-version = 1.0
+//	+version = 1.1
+//	@@ [server] #0 @@
+//	 listen = :80
+//	+listen = :443
+//	@@ [server] #1 @@
+//	-[server]
+//	-listen = :8080
+//
 // Configuration EBNF
 //
 // Here lies the EBNF for configuration syntax:
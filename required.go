@@ -0,0 +1,119 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// Missing describes a required key or section that is missing from the configuration.
+type Missing struct {
+	// Section is the name of the section; empty for the global section.
+	Section string
+	// Index is the position of the section when it is repeated and filled into a slice; otherwise -1.
+	Index int
+	// Key is the name of the key; empty when the section itself is missing.
+	Key string
+}
+
+// String returns the missing key as section.key, section[index].key, or key; or the missing section as [section].
+func (me Missing) String() string {
+	section := me.Section
+	if me.Index >= 0 {
+		section = fmt.Sprintf("%v[%v]", section, me.Index)
+	}
+	if me.Key == "" {
+		return "[" + section + "]"
+	} else if section == "" {
+		return me.Key
+	}
+	return section + "." + me.Key
+}
+
+// RequiredError is returned from Conf.Fill, Conf.FillByTag, and Conf.FillWith when required keys or sections
+// are missing; it lists every missing key and section in the order of the struct fields.  The target is not
+// filled when a RequiredError is returned.
+type RequiredError struct {
+	Missing []Missing
+}
+
+// Error returns the error string.
+func (me *RequiredError) Error() string {
+	strs := make([]string, len(me.Missing))
+	for k, missing := range me.Missing {
+		strs[k] = missing.String()
+	}
+	return "Missing required configuration: " + strings.Join(strs, ", ")
+}
+
+// required returns true if field is required; tag is the struct tag used to fill.
+func required(field set.Field, tag string) bool {
+	return (tag != "" && tagOption(field.TagValue, "required")) || field.Field.Tag.Get("required") == "true"
+}
+
+// checkRequired returns a *RequiredError if any required fields are missing from m; present contains the
+// sections that were in m before defaults were applied.
+func checkRequired(m map[string][]map[string][]string, present map[string]bool, fields []set.Field, tag string) error {
+	var rv []Missing
+	global := m[""][len(m[""])-1]
+	for _, field := range fields {
+		name := fieldName(field, tag)
+		if field.Value.IsScalar || (field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
+			if _, ok := global[name]; !ok && required(field, tag) {
+				rv = append(rv, Missing{Index: -1, Key: name})
+			}
+			continue
+		}
+		//
+		sections := m[name]
+		if field.Value.IsStruct {
+			if !present[name] {
+				if required(field, tag) {
+					rv = append(rv, Missing{Section: name, Index: -1})
+				}
+				continue
+			}
+			rv = append(rv, missingKeys(sections[len(sections)-1], typeFields(field.Value.Type, tag), tag, name, -1)...)
+		} else if isStructMap(field) {
+			entries := mapSections(m, name)
+			if len(entries) == 0 && required(field, tag) {
+				rv = append(rv, Missing{Section: name, Index: -1})
+			}
+			sectionFields := typeFields(field.Value.ElemTypeInfo.Type, tag)
+			for _, entry := range entries {
+				rv = append(rv, missingKeys(entry.section, sectionFields, tag, entry.name, entry.index)...)
+			}
+		} else if isMap(field) {
+			if len(sections) == 0 && required(field, tag) {
+				rv = append(rv, Missing{Section: name, Index: -1})
+			}
+		} else if field.Value.IsSlice && field.Value.ElemTypeInfo.IsStruct {
+			if len(sections) == 0 && required(field, tag) {
+				rv = append(rv, Missing{Section: name, Index: -1})
+			}
+			sectionFields := typeFields(field.Value.ElemTypeInfo.Type, tag)
+			for k, section := range sections {
+				rv = append(rv, missingKeys(section, sectionFields, tag, name, k)...)
+			}
+		}
+	}
+	if len(rv) == 0 {
+		return nil
+	}
+	return &RequiredError{Missing: rv}
+}
+
+// missingKeys returns the required fields that are missing from the section.
+func missingKeys(section map[string][]string, fields []set.Field, tag string, name string, index int) []Missing {
+	var rv []Missing
+	for _, field := range fields {
+		key := fieldName(field, tag)
+		if isSubsection(field, tag) {
+			continue
+		} else if _, ok := section[key]; !ok && required(field, tag) {
+			rv = append(rv, Missing{Section: name, Index: index, Key: key})
+		}
+	}
+	return rv
+}
@@ -0,0 +1,107 @@
+package conf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+func TestConf_FillRequired(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `
+name = example
+
+[database]
+user = root
+
+[server]
+listen = :80
+
+[server]
+timeout = 5
+`
+	type T struct {
+		Name  string `conf:"name,required"`
+		Owner string `conf:"owner,required"`
+		//
+		Database struct {
+			DSN  string `conf:"dsn,required"`
+			User string `conf:"user,required"`
+		} `conf:"database"`
+		//
+		Servers []struct {
+			Listen string `conf:"listen,required"`
+		} `conf:"server"`
+		//
+		Cache struct {
+			Host string `conf:"host"`
+		} `conf:"cache,required"`
+	}
+	c, err := conf.String(s)
+	chk.NoError(err)
+	{
+		var t T
+		err = c.FillByTag("conf", &t)
+		chk.Error(err)
+		var required *conf.RequiredError
+		chk.True(errors.As(err, &required))
+		chk.Equal([]conf.Missing{
+			{Index: -1, Key: "owner"},
+			{Section: "database", Index: -1, Key: "dsn"},
+			{Section: "server", Index: 1, Key: "listen"},
+			{Section: "cache", Index: -1},
+		}, required.Missing)
+		chk.Equal("Missing required configuration: owner, database.dsn, server[1].listen, [cache]", err.Error())
+		chk.Equal("", t.Name)
+	}
+	{
+		// Defaults and options do not interfere with names.
+		type U struct {
+			Name     string `conf:"name,required"`
+			Owner    string `conf:"owner,required" default:"nobody"`
+			Database struct {
+				User string `conf:"user,required"`
+			} `conf:"database,required"`
+			Servers []struct {
+				Listen string `conf:"listen" default:":8000"`
+			} `conf:"server,required"`
+		}
+		var u U
+		err = c.FillByTag("conf", &u)
+		chk.NoError(err)
+		chk.Equal("example", u.Name)
+		chk.Equal("nobody", u.Owner)
+		chk.Equal("root", u.Database.User)
+		chk.Equal(2, len(u.Servers))
+		chk.Equal(":8000", u.Servers[1].Listen)
+	}
+	{
+		// Sections created by defaults do not satisfy required sections.
+		c, err := conf.String("a = 1\n")
+		chk.NoError(err)
+		var u struct {
+			DB struct {
+				Host string `conf:"host" default:"localhost"`
+			} `conf:"db,required"`
+		}
+		err = c.FillByTag("conf", &u)
+		var required *conf.RequiredError
+		if chk.True(errors.As(err, &required)) {
+			chk.Equal([]conf.Missing{{Section: "db", Index: -1}}, required.Missing)
+		}
+	}
+	{
+		// Separate tag when filling by field name.
+		type U struct {
+			Missing string `required:"true"`
+		}
+		var u U
+		err = c.Fill(&u)
+		chk.Error(err)
+		chk.Equal("Missing required configuration: Missing", err.Error())
+	}
+}
@@ -0,0 +1,194 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// Section is a single section of a configuration; use Conf.Sections() to access repeated sections.
+//
+// The zero value is an empty section.
+type Section struct {
+	name       string
+	subsection string
+	section    parser.Section
+}
+
+// ValueError is returned from the typed getters when a key is missing or its value can not be converted.
+type ValueError struct {
+	// Section is the name of the section; empty for the global section.
+	Section string
+	// Key is the name of the key.
+	Key string
+	// Position is where the value was defined; the zero value when the key is missing.
+	Position parser.Position
+	// Value is the value that could not be converted.
+	Value string
+	// Err is the conversion error; nil when the key is missing.
+	Err error
+}
+
+// Error returns the error string.
+func (me *ValueError) Error() string {
+	name := Missing{Section: me.Section, Index: -1, Key: me.Key}.String()
+	if me.Err == nil {
+		return "Missing key " + name
+	}
+	return fmt.Sprintf("%v: Invalid value for %v= %v; %v", me.Position, name, me.Value, me.Err)
+}
+
+// Missing returns true if the error is due to a missing key.
+func (me *ValueError) Missing() bool {
+	return me.Err == nil
+}
+
+// Unwrap returns the conversion error.
+func (me *ValueError) Unwrap() error {
+	return me.Err
+}
+
+// Name returns the name of the section; empty for the global section.
+func (me Section) Name() string {
+	return me.name
+}
+
+// Subsection returns the subsection named in the section header; empty when the header did not name one.
+func (me Section) Subsection() string {
+	return me.subsection
+}
+
+// Get returns the last value of key and true if the key exists.
+func (me Section) Get(key string) (string, bool) {
+	if value, ok := me.section[key]; ok && len(value.Slice) > 0 {
+		return value.Last, true
+	}
+	return "", false
+}
+
+// GetAll returns every value of key; it returns nil if the key does not exist.
+func (me Section) GetAll(key string) []string {
+	if value, ok := me.section[key]; ok && len(value.Slice) > 0 {
+		return append([]string{}, value.Slice...)
+	}
+	return nil
+}
+
+// GetInt returns the last value of key converted to an int.
+func (me Section) GetInt(key string) (int, error) {
+	var rv int
+	err := me.to(key, func(str string) error {
+		return set.V(&rv).To(str)
+	})
+	return rv, err
+}
+
+// GetBool returns the last value of key converted to a bool.
+func (me Section) GetBool(key string) (bool, error) {
+	var rv bool
+	err := me.to(key, func(str string) error {
+		return set.V(&rv).To(str)
+	})
+	return rv, err
+}
+
+// GetDuration returns the last value of key converted to a time.Duration with time.ParseDuration.
+//
+// Unlike Fill, which only accepts an integer number of nanoseconds for a time.Duration field, values such as
+// 5s or 1h30m are accepted.
+func (me Section) GetDuration(key string) (time.Duration, error) {
+	var rv time.Duration
+	err := me.to(key, func(str string) (err error) {
+		rv, err = time.ParseDuration(str)
+		return err
+	})
+	return rv, err
+}
+
+// to calls fn with the last value of key and returns a *ValueError if the key is missing or fn fails.
+func (me Section) to(key string, fn func(string) error) error {
+	value, ok := me.section[key]
+	if !ok || len(value.Slice) == 0 {
+		return &ValueError{Section: me.name, Key: key}
+	}
+	if err := fn(value.Last); err != nil {
+		var pos parser.Position
+		if size := len(value.Positions); size > 0 {
+			pos = value.Positions[size-1]
+		}
+		return &ValueError{Section: me.name, Key: key, Position: pos, Value: value.Last, Err: err}
+	}
+	return nil
+}
+
+// Sections returns every section with the given name in the order they appeared; use "" for the global section.
+func (me *Conf) Sections(name string) []Section {
+	if me == nil {
+		return nil
+	}
+	block, ok := me.parsed[name]
+	if !ok {
+		return nil
+	}
+	rv := make([]Section, len(block.Slice))
+	for k, section := range block.Slice {
+		rv[k] = Section{name: name, subsection: block.Subsection(k), section: section}
+	}
+	return rv
+}
+
+// section returns the last section with the given name; the section is empty if it does not exist.
+func (me *Conf) section(name string) Section {
+	if me != nil {
+		if block, ok := me.parsed[name]; ok {
+			return Section{name: name, subsection: block.Subsection(len(block.Slice) - 1), section: block.Last}
+		}
+	}
+	return Section{name: name}
+}
+
+// Get returns the last value of key in the last section with the given name and true if the key exists;
+// use "" for the global section.
+func (me *Conf) Get(section, key string) (string, bool) {
+	return me.section(section).Get(key)
+}
+
+// GetAll returns every value of key in the last section with the given name; it returns nil if the key
+// does not exist.
+func (me *Conf) GetAll(section, key string) []string {
+	return me.section(section).GetAll(key)
+}
+
+// GetInt returns the last value of key in the last section with the given name converted to an int.
+//
+// A missing key or a value that can not be converted is returned as *ValueError.
+func (me *Conf) GetInt(section, key string) (int, error) {
+	if me == nil {
+		return 0, errors.NilReceiver()
+	}
+	return me.section(section).GetInt(key)
+}
+
+// GetBool returns the last value of key in the last section with the given name converted to a bool.
+//
+// A missing key or a value that can not be converted is returned as *ValueError.
+func (me *Conf) GetBool(section, key string) (bool, error) {
+	if me == nil {
+		return false, errors.NilReceiver()
+	}
+	return me.section(section).GetBool(key)
+}
+
+// GetDuration returns the last value of key in the last section with the given name converted to a
+// time.Duration with time.ParseDuration; see Section.GetDuration.
+//
+// A missing key or a value that can not be converted is returned as *ValueError.
+func (me *Conf) GetDuration(section, key string) (time.Duration, error) {
+	if me == nil {
+		return 0, errors.NilReceiver()
+	}
+	return me.section(section).GetDuration(key)
+}
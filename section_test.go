@@ -0,0 +1,94 @@
+package conf_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestConf_Get(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `name = example
+hosts = a
+hosts = b
+debug = true
+
+[server]
+port = 80
+timeout = 5s
+
+[server]
+port = eighty
+timeout = 5
+`
+	c, err := conf.String(s)
+	chk.NoError(err)
+	{
+		str, ok := c.Get("", "name")
+		chk.True(ok)
+		chk.Equal("example", str)
+		str, ok = c.Get("", "missing")
+		chk.False(ok)
+		chk.Equal("", str)
+		str, ok = c.Get("missing", "name")
+		chk.False(ok)
+		chk.Equal("", str)
+		chk.Equal([]string{"a", "b"}, c.GetAll("", "hosts"))
+		chk.Nil(c.GetAll("", "missing"))
+	}
+	{
+		b, err := c.GetBool("", "debug")
+		chk.NoError(err)
+		chk.True(b)
+		_, err = c.GetBool("", "name")
+		chk.Error(err)
+	}
+	{
+		// The last section is used.
+		_, err := c.GetInt("server", "port")
+		chk.Error(err)
+		var valueErr *conf.ValueError
+		chk.True(errors.As(err, &valueErr))
+		chk.False(valueErr.Missing())
+		chk.Equal(parser.Position{Line: 11, Column: 1}, valueErr.Position)
+		chk.Equal("eighty", valueErr.Value)
+		chk.Contains(err.Error(), "11:1: Invalid value for server.port= eighty; ")
+		//
+		_, err = c.GetDuration("server", "timeout")
+		chk.Error(err)
+		//
+		_, err = c.GetInt("server", "missing")
+		chk.Error(err)
+		chk.True(errors.As(err, &valueErr))
+		chk.True(valueErr.Missing())
+		chk.Equal("Missing key server.missing", err.Error())
+	}
+	{
+		sections := c.Sections("server")
+		chk.Equal(2, len(sections))
+		chk.Equal("server", sections[0].Name())
+		port, err := sections[0].GetInt("port")
+		chk.NoError(err)
+		chk.Equal(80, port)
+		timeout, err := sections[0].GetDuration("timeout")
+		chk.NoError(err)
+		chk.Equal(5*time.Second, timeout)
+		//
+		chk.Equal(1, len(c.Sections("")))
+		chk.Nil(c.Sections("missing"))
+	}
+	{
+		var c *conf.Conf
+		_, err := c.GetInt("", "name")
+		chk.Error(err)
+		_, ok := c.Get("", "name")
+		chk.False(ok)
+		chk.Nil(c.Sections(""))
+	}
+}
@@ -0,0 +1,125 @@
+package conf
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// keyCallback is a callback registered with Store.OnChange.
+type keyCallback struct {
+	section, key string
+	fn           func(old, new []string)
+}
+
+// Store holds the current configuration for concurrent use; reads are lock free and never observe a partially
+// replaced configuration.
+//
+// The zero value is ready to use and holds no configuration.
+type Store struct {
+	// New returns a new pointer to struct that is filled from the configuration by Replace; when nil only the
+	// Conf is stored.
+	New func() interface{}
+	// FillOptions used to fill the value returned from New.
+	FillOptions FillOptions
+	// Validate is called by Replace with the new Snapshot before it is stored; an error prevents the replacement.
+	Validate func(snapshot *Snapshot) error
+
+	current atomic.Value
+
+	mut       sync.Mutex
+	callbacks []keyCallback
+}
+
+// Load returns the current configuration or nil if Replace has not succeeded.
+func (me *Store) Load() *Snapshot {
+	if me == nil {
+		return nil
+	}
+	snapshot, _ := me.current.Load().(*Snapshot)
+	return snapshot
+}
+
+// Conf returns the current Conf or nil.
+func (me *Store) Conf() *Conf {
+	if snapshot := me.Load(); snapshot != nil {
+		return snapshot.Conf
+	}
+	return nil
+}
+
+// Value returns the current value filled from the configuration or nil.
+func (me *Store) Value() interface{} {
+	if snapshot := me.Load(); snapshot != nil {
+		return snapshot.Value
+	}
+	return nil
+}
+
+// OnChange registers fn to be called by Replace when the values of key in the last section with the given name
+// change; use "" for the global section.  fn receives the values before and after the replacement and either
+// is nil when the key does not exist.  Callbacks are called in the order they were registered without holding
+// the Store's lock so they may call any of the Store's methods; a callback registered by a callback is first
+// called by the next Replace.
+func (me *Store) OnChange(section, key string, fn func(old, new []string)) {
+	if me == nil || fn == nil {
+		return
+	}
+	me.mut.Lock()
+	defer me.mut.Unlock()
+	me.callbacks = append(me.callbacks, keyCallback{section: section, key: key, fn: fn})
+}
+
+// Replace fills a new value from c, validates it, and stores it as the current configuration; callbacks
+// registered with OnChange are then called for keys whose values changed.  The current configuration is
+// unchanged when an error is returned.
+//
+// Callbacks from concurrent calls to Replace are not ordered with respect to each other; each receives the values
+// before and after its own replacement, so the old values may not match the new values of the last callback.
+// Serialize calls to Replace when callbacks depend on the order.
+func (me *Store) Replace(c *Conf) error {
+	if me == nil {
+		return errors.NilReceiver()
+	} else if c == nil {
+		return errors.NilArgument("c")
+	}
+	snapshot := &Snapshot{Conf: c}
+	if me.New != nil {
+		snapshot.Value = me.New()
+		if err := c.FillWith(snapshot.Value, me.FillOptions); err != nil {
+			return err
+		}
+	}
+	if me.Validate != nil {
+		if err := me.Validate(snapshot); err != nil {
+			return err
+		}
+	}
+	//
+	me.mut.Lock()
+	old := me.Conf()
+	me.current.Store(snapshot)
+	callbacks := append([]keyCallback{}, me.callbacks...)
+	me.mut.Unlock()
+	for _, callback := range callbacks {
+		before, after := old.GetAll(callback.section, callback.key), c.GetAll(callback.section, callback.key)
+		if !equalStrings(before, after) {
+			callback.fn(before, after)
+		}
+	}
+	return nil
+}
+
+// equalStrings returns true if a and b contain the same strings; nil and empty are equal.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
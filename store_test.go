@@ -0,0 +1,121 @@
+package conf_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+func TestStore(t *testing.T) {
+	chk := assert.New(t)
+	//
+	type T struct {
+		Name  string   `conf:"name"`
+		Port  int      `conf:"port"`
+		Hosts []string `conf:"hosts"`
+	}
+	type change struct {
+		old, new []string
+	}
+	var changes []change
+	store := &conf.Store{
+		New:         func() interface{} { return &T{} },
+		FillOptions: conf.FillOptions{Tag: "conf"},
+		Validate: func(snapshot *conf.Snapshot) error {
+			if snapshot.Value.(*T).Port == 0 {
+				return errors.New("port is required")
+			}
+			return nil
+		},
+	}
+	chk.Nil(store.Load())
+	chk.Nil(store.Conf())
+	chk.Nil(store.Value())
+	store.OnChange("", "port", func(old, new []string) {
+		changes = append(changes, change{old, new})
+	})
+	store.OnChange("server", "listen", func(old, new []string) {
+		changes = append(changes, change{old, new})
+	})
+	load := func(s string) *conf.Conf {
+		c, err := conf.String(s)
+		chk.NoError(err)
+		return c
+	}
+	{
+		chk.NoError(store.Replace(load("name = a\nport = 80\n")))
+		chk.Equal(&T{Name: "a", Port: 80}, store.Value())
+		chk.Equal([]change{{nil, []string{"80"}}}, changes)
+	}
+	{
+		// Unchanged keys do not call back.
+		changes = nil
+		chk.NoError(store.Replace(load("name = b\nport = 80\n")))
+		chk.Equal(&T{Name: "b", Port: 80}, store.Value())
+		chk.Nil(changes)
+		chk.NoError(store.Replace(load("name = b\nport = 81\n[server]\nlisten = :80\n")))
+		chk.Equal([]change{{[]string{"80"}, []string{"81"}}, {nil, []string{":80"}}}, changes)
+	}
+	{
+		// Invalid configurations are not stored.
+		changes = nil
+		previous := store.Load()
+		chk.Error(store.Replace(load("name = c\n")))
+		chk.Error(store.Replace(load("port = eighty\n")))
+		chk.Error(store.Replace(nil))
+		chk.True(previous == store.Load())
+		chk.Nil(changes)
+	}
+	{
+		// Concurrent readers see complete values.
+		plain := &conf.Store{New: func() interface{} { return &T{} }}
+		chk.NoError(plain.Replace(load("Name = x\nHosts = a\nHosts = b\n")))
+		var wg sync.WaitGroup
+		for k := 0; k < 4; k++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 100; n++ {
+					v := plain.Value().(*T)
+					chk.Equal(2, len(v.Hosts))
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 10; n++ {
+					chk.NoError(plain.Replace(load("Name = y\nHosts = c\nHosts = d\n")))
+				}
+			}()
+		}
+		wg.Wait()
+		chk.Equal("y", plain.Value().(*T).Name)
+	}
+	{
+		// Callbacks may call the Store's methods.
+		plain, calls := &conf.Store{}, []string{}
+		plain.OnChange("", "name", func(old, new []string) {
+			calls = append(calls, "name")
+			plain.OnChange("", "port", func(old, new []string) {
+				calls = append(calls, "port")
+			})
+			chk.True(plain.Conf() != nil)
+			if new[0] == "a" {
+				chk.NoError(plain.Replace(load("name = b\nport = 1\n")))
+			}
+		})
+		chk.NoError(plain.Replace(load("name = a\n")))
+		chk.Equal([]string{"name", "name", "port"}, calls)
+		name, _ := plain.Conf().Get("", "name")
+		chk.Equal("b", name)
+	}
+	{
+		var store *conf.Store
+		chk.Error(store.Replace(load("")))
+		chk.Nil(store.Load())
+		store.OnChange("", "key", func(old, new []string) {})
+	}
+}
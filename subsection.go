@@ -0,0 +1,29 @@
+package conf
+
+import (
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// subsectionKey is the key holding the subsection of a section in the map returned from Conf.sections; keys
+// in configuration are never empty.
+const subsectionKey = ""
+
+// isSubsection returns true if field receives the subsection of its section rather than a key; tag is the
+// struct tag used to fill.
+func isSubsection(field set.Field, tag string) bool {
+	return tag != "" && tagOption(field.TagValue, "name")
+}
+
+// sections returns the parsed configuration as a map where each section naming a subsection also has the
+// subsection under subsectionKey.
+func (me *Conf) sections() map[string][]map[string][]string {
+	m := me.parsed.Map()
+	for name, block := range me.parsed {
+		for k, subsection := range block.Subsections {
+			if subsection != "" && k < len(m[name]) {
+				m[name][k][subsectionKey] = []string{subsection}
+			}
+		}
+	}
+	return m
+}
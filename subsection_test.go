@@ -0,0 +1,160 @@
+package conf_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestConf_FillSubsections(t *testing.T) {
+	chk := assert.New(t)
+	//
+	loader := conf.Loader{Parser: parser.DefaultParser}
+	loader.Parser.Subsections = parser.SubsectionsQuoted
+	s := `
+[remote "origin"]
+url = git@example.com:origin
+
+[remote "upstream"]
+url = git@example.com:upstream
+fetch = +refs/heads/*
+
+[remote]
+url = plain
+
+[branch "main"]
+remote = origin
+`
+	type Remote struct {
+		Name  string `conf:",name"`
+		URL   string `conf:"url"`
+		Fetch string `conf:"fetch" default:"+refs/heads/main"`
+	}
+	c, err := loader.String(s)
+	chk.NoError(err)
+	{
+		var t struct {
+			Remotes []Remote `conf:"remote"`
+			Branch  struct {
+				Name   string `conf:"branch,name"`
+				Remote string `conf:"remote"`
+			} `conf:"branch"`
+		}
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal([]Remote{
+			{Name: "origin", URL: "git@example.com:origin", Fetch: "+refs/heads/main"},
+			{Name: "upstream", URL: "git@example.com:upstream", Fetch: "+refs/heads/*"},
+			{URL: "plain", Fetch: "+refs/heads/main"},
+		}, t.Remotes)
+		chk.Equal("main", t.Branch.Name)
+		chk.Equal("origin", t.Branch.Remote)
+	}
+	{
+		// Maps are keyed by subsection.
+		var t struct {
+			Remotes map[string]Remote `conf:"remote"`
+		}
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal(map[string]Remote{
+			"origin":   {Name: "origin", URL: "git@example.com:origin", Fetch: "+refs/heads/main"},
+			"upstream": {Name: "upstream", URL: "git@example.com:upstream", Fetch: "+refs/heads/*"},
+		}, t.Remotes)
+	}
+	{
+		// Sections named with a prefix also fill the name field.
+		c, err := conf.String("[backend web]\nhost = localhost\n")
+		chk.NoError(err)
+		var t struct {
+			Backends map[string]struct {
+				Name string `conf:",name"`
+				Host string `conf:"host"`
+			} `conf:"backend"`
+		}
+		chk.NoError(c.FillByTag("conf", &t))
+		chk.Equal("web", t.Backends["web"].Name)
+		chk.Equal("localhost", t.Backends["web"].Host)
+	}
+	{
+		// Sections without subsections are unused by a map; required keys are reported by index.
+		var t struct {
+			Remotes map[string]struct {
+				URL   string `conf:"url"`
+				Fetch string `conf:"fetch,required"`
+			} `conf:"remote"`
+			Branch map[string]struct {
+				Remote string `conf:"remote"`
+			} `conf:"branch"`
+		}
+		unused, err := c.Unused(&t, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		if chk.Len(unused, 1) {
+			chk.Equal(conf.Unused{Section: "remote", Index: 2, Position: parser.Position{Line: 9, Column: 1}}, unused[0])
+		}
+		err = c.FillByTag("conf", &t)
+		var required *conf.RequiredError
+		if chk.True(errors.As(err, &required)) {
+			chk.Equal([]conf.Missing{{Section: "remote", Index: 0, Key: "fetch"}}, required.Missing)
+		}
+	}
+	{
+		// Getters.
+		sections := c.Sections("remote")
+		if chk.Len(sections, 3) {
+			chk.Equal("remote", sections[0].Name())
+			chk.Equal("origin", sections[0].Subsection())
+			chk.Equal("upstream", sections[1].Subsection())
+			chk.Equal("", sections[2].Subsection())
+		}
+	}
+	{
+		// Marshal skips the name field.
+		var t struct {
+			Name string `conf:",name"`
+			URL  string `conf:"url"`
+		}
+		t.Name, t.URL = "origin", "x"
+		b, err := conf.MarshalByTag("conf", &t)
+		chk.NoError(err)
+		chk.Equal("url = x\n", string(b))
+	}
+}
+
+func TestLoad_subsections(t *testing.T) {
+	chk := assert.New(t)
+	//
+	loader := conf.Loader{Parser: parser.DefaultParser}
+	loader.Parser.Subsections = parser.SubsectionsSpace
+	// Sections naming a subsection merge by subsection rather than by position.
+	c, err := loader.Load(
+		conf.StringSource("[remote origin]\nurl = a\n\n[remote upstream]\nurl = b\n"),
+		conf.StringSource("[remote upstream]\nurl = c\n\n[remote fork]\nurl = d\n"),
+	)
+	chk.NoError(err)
+	var names, urls []string
+	for _, section := range c.Sections("remote") {
+		url, _ := section.Get("url")
+		names, urls = append(names, section.Subsection()), append(urls, url)
+	}
+	chk.Equal("origin upstream fork", strings.Join(names, " "))
+	chk.Equal("a c d", strings.Join(urls, " "))
+	//
+	// Sections without a subsection merge by position among sections without a subsection.
+	loader.Parser.Subsections = parser.SubsectionsQuoted
+	c, err = loader.Load(
+		conf.StringSource("[a]\nk = plain\n[a \"x\"]\nk = x\n"),
+		conf.StringSource("[a \"x\"]\nk = x2\n[a]\nk = plain2\n[a]\nk = plain3\n"),
+	)
+	chk.NoError(err)
+	names, urls = nil, nil
+	for _, section := range c.Sections("a") {
+		k, _ := section.Get("k")
+		names, urls = append(names, section.Subsection()), append(urls, k)
+	}
+	chk.Equal([]string{"", "x", ""}, names)
+	chk.Equal([]string{"plain2", "x2", "plain3"}, urls)
+}
@@ -0,0 +1,165 @@
+package conf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+	"github.com/nofeaturesonlybugs/set"
+)
+
+// Unused describes a key or section in the configuration that is not consumed by any struct field.
+type Unused struct {
+	// Section is the name of the section; empty for the global section.
+	Section string
+	// Index is the position of the section among sections with the same name.
+	Index int
+	// Key is the name of the key; empty when the entire section is unused.
+	Key string
+	// Position is where the key or section was defined.
+	Position parser.Position
+}
+
+// String returns the unused key as section.key or key, or the unused section as [section], followed by its position.
+func (me Unused) String() string {
+	str := "[" + me.Section + "]"
+	if me.Key != "" && me.Section == "" {
+		str = me.Key
+	} else if me.Key != "" {
+		str = me.Section + "." + me.Key
+	}
+	return fmt.Sprintf("%v at %v", str, me.Position)
+}
+
+// UnusedError is returned from Conf.FillWith when FillOptions.Strict is true and the configuration contains
+// keys or sections that are not consumed by the target.
+type UnusedError struct {
+	Unused []Unused
+}
+
+// Error returns the error string.
+func (me *UnusedError) Error() string {
+	strs := make([]string, len(me.Unused))
+	for k, unused := range me.Unused {
+		strs[k] = unused.String()
+	}
+	return "Unused configuration: " + strings.Join(strs, ", ")
+}
+
+// Unused returns the keys and sections in the configuration that would not be consumed by filling target
+// with opts; target should be a pointer to struct and is not modified.
+func (me *Conf) Unused(target interface{}, opts FillOptions) ([]Unused, error) {
+	if me == nil {
+		return nil, errors.NilReceiver()
+	}
+	var fields []set.Field
+	if value := set.V(target); opts.Tag == "" {
+		fields = value.Fields()
+	} else {
+		fields = value.FieldsByTag(opts.Tag)
+	}
+	return me.unused(fields, opts.Tag), nil
+}
+
+// unused returns the keys and sections in the configuration that are not consumed by fields.
+func (me *Conf) unused(fields []set.Field, tag string) []Unused {
+	// Keys consumed in each section; the global section is "".
+	used := map[string]map[string]bool{"": {}}
+	// Keys consumed in individual sections by maps of structs; keyed by section name and index.
+	usedAt := map[string]map[int]map[string]bool{}
+	// Sections whose keys are all consumed by maps.
+	all := map[string]bool{}
+	// Sections where only the last instance is consumed; struct fields, maps of scalars, and repeated sections
+	// in maps of structs are filled from the last section with the name.  Every instance is consumed by a slice
+	// of structs.
+	last, every := map[string]bool{}, map[string]bool{}
+	m := me.sections()
+	for _, field := range fields {
+		name := fieldName(field, tag)
+		if field.Value.IsScalar || (field.Value.IsSlice && field.Value.ElemTypeInfo.IsScalar) {
+			used[""][name] = true
+			continue
+		}
+		var sectionFields []set.Field
+		if isStructMap(field) {
+			sectionFields = typeFields(field.Value.ElemTypeInfo.Type, tag)
+			for _, entry := range mapSections(m, name) {
+				keys := map[string]bool{}
+				for _, sectionField := range sectionFields {
+					keys[fieldName(sectionField, tag)] = true
+				}
+				if entry.index == -1 {
+					used[entry.name], last[entry.name] = keys, true
+					continue
+				} else if usedAt[entry.name] == nil {
+					usedAt[entry.name] = map[int]map[string]bool{}
+				}
+				usedAt[entry.name][entry.index] = keys
+			}
+			continue
+		} else if isMap(field) {
+			all[name], last[name] = true, true
+			continue
+		} else if field.Value.IsStruct {
+			sectionFields, last[name] = typeFields(field.Value.Type, tag), true
+		} else if field.Value.IsSlice && field.Value.ElemTypeInfo.IsStruct {
+			sectionFields, every[name] = typeFields(field.Value.ElemTypeInfo.Type, tag), true
+		} else {
+			continue
+		}
+		if used[name] == nil {
+			used[name] = map[string]bool{}
+		}
+		for _, sectionField := range sectionFields {
+			used[name][fieldName(sectionField, tag)] = true
+		}
+	}
+	//
+	var rv []Unused
+	for name, block := range me.parsed {
+		for k, section := range block.Slice {
+			keys, ok := usedAt[name][k]
+			if !ok && last[name] && !every[name] && k < len(block.Slice)-1 {
+				// The section is replaced by a later section with the same name.
+				rv = append(rv, Unused{Section: name, Index: k, Position: sectionPosition(block, k)})
+				continue
+			} else if !ok && all[name] {
+				continue
+			} else if !ok {
+				keys, ok = used[name]
+			}
+			if !ok {
+				rv = append(rv, Unused{Section: name, Index: k, Position: sectionPosition(block, k)})
+				continue
+			}
+			for key, value := range section {
+				if !keys[key] {
+					var pos parser.Position
+					if len(value.Positions) > 0 {
+						pos = value.Positions[0]
+					}
+					rv = append(rv, Unused{Section: name, Index: k, Key: key, Position: pos})
+				}
+			}
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].Section != rv[j].Section {
+			return rv[i].Section < rv[j].Section
+		} else if rv[i].Index != rv[j].Index {
+			return rv[i].Index < rv[j].Index
+		}
+		return rv[i].Key < rv[j].Key
+	})
+	return rv
+}
+
+// sectionPosition returns block.Positions[k] if it exists.
+func sectionPosition(block *parser.SectionBlock, k int) parser.Position {
+	if k < len(block.Positions) {
+		return block.Positions[k]
+	}
+	return parser.Position{}
+}
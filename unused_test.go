@@ -0,0 +1,141 @@
+package conf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+	"github.com/nofeaturesonlybugs/conf/parser"
+)
+
+func TestConf_Unused(t *testing.T) {
+	chk := assert.New(t)
+	//
+	s := `name = example
+nmae = typo
+
+[server]
+listen = :80
+
+[server]
+listne = :81
+
+[unknown]
+key = value
+`
+	type T struct {
+		Name    string `conf:"name"`
+		Servers []struct {
+			Listen string `conf:"listen"`
+		} `conf:"server"`
+	}
+	c, err := conf.String(s)
+	chk.NoError(err)
+	expect := []conf.Unused{
+		{Key: "nmae", Position: parser.Position{Line: 2, Column: 1}},
+		{Section: "server", Index: 1, Key: "listne", Position: parser.Position{Line: 8, Column: 1}},
+		{Section: "unknown", Position: parser.Position{Line: 10, Column: 1}},
+	}
+	{
+		var t T
+		unused, err := c.Unused(&t, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		chk.Equal(expect, unused)
+	}
+	{
+		// Not strict.
+		var t T
+		err = c.FillByTag("conf", &t)
+		chk.NoError(err)
+		chk.Equal("example", t.Name)
+	}
+	{
+		var t T
+		err = c.FillWith(&t, conf.FillOptions{Tag: "conf", Strict: true})
+		chk.Error(err)
+		var unused *conf.UnusedError
+		chk.True(errors.As(err, &unused))
+		chk.Equal(expect, unused.Unused)
+		chk.Equal("Unused configuration: nmae at 2:1, server.listne at 8:1, [unknown] at 10:1", err.Error())
+	}
+	{
+		// Nothing unused.
+		u := struct {
+			Name    string `conf:"name"`
+			Nmae    string `conf:"nmae"`
+			Servers []struct {
+				Listen string `conf:"listen"`
+				Listne string `conf:"listne"`
+			} `conf:"server"`
+			Unknown struct {
+				Key string `conf:"key"`
+			} `conf:"unknown"`
+		}{}
+		err = c.FillWith(&u, conf.FillOptions{Tag: "conf", Strict: true})
+		chk.NoError(err)
+	}
+	{
+		var c *conf.Conf
+		_, err := c.Unused(&struct{}{}, conf.FillOptions{})
+		chk.Error(err)
+	}
+}
+
+func TestConf_UnusedRepeated(t *testing.T) {
+	chk := assert.New(t)
+	//
+	// Only the last repeated section is filled into a struct or map; earlier sections are unused.
+	{
+		c, err := conf.String("[database]\nhost = a\nport = 1\n[database]\nhost = b\n")
+		chk.NoError(err)
+		var v struct {
+			DB struct {
+				Host string `conf:"host"`
+				Port string `conf:"port"`
+			} `conf:"database"`
+		}
+		err = c.FillWith(&v, conf.FillOptions{Tag: "conf", Strict: true})
+		var unused *conf.UnusedError
+		if chk.True(errors.As(err, &unused)) {
+			chk.Equal([]conf.Unused{{Section: "database", Position: parser.Position{Line: 1, Column: 1}}}, unused.Unused)
+		}
+		chk.NoError(c.FillByTag("conf", &v))
+		chk.Equal("b", v.DB.Host)
+		chk.Equal("", v.DB.Port)
+		// A slice consumes every section.
+		var u struct {
+			DB []struct {
+				Host string `conf:"host"`
+				Port string `conf:"port"`
+			} `conf:"database"`
+		}
+		chk.NoError(c.FillWith(&u, conf.FillOptions{Tag: "conf", Strict: true}))
+	}
+	{
+		c, err := conf.String("[headers]\na = 1\n[headers]\nb = 2\n")
+		chk.NoError(err)
+		var v struct {
+			Headers map[string]string `conf:"headers"`
+		}
+		unused, err := c.Unused(&v, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		chk.Equal([]conf.Unused{{Section: "headers", Position: parser.Position{Line: 1, Column: 1}}}, unused)
+		chk.NoError(c.FillByTag("conf", &v))
+		chk.Equal(map[string]string{"b": "2"}, v.Headers)
+	}
+	{
+		c, err := conf.String("[backend web]\nhost = a\n[backend web]\nport = 1\n")
+		chk.NoError(err)
+		var v struct {
+			Backends map[string]struct {
+				Host string `conf:"host"`
+				Port string `conf:"port"`
+			} `conf:"backend"`
+		}
+		unused, err := c.Unused(&v, conf.FillOptions{Tag: "conf"})
+		chk.NoError(err)
+		chk.Equal([]conf.Unused{{Section: "backend web", Position: parser.Position{Line: 1, Column: 1}}}, unused)
+	}
+}
@@ -0,0 +1,283 @@
+package conf
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nofeaturesonlybugs/conf/parser"
+	"github.com/nofeaturesonlybugs/errors"
+)
+
+// Snapshot is a configuration loaded by a Watcher; a Snapshot is never modified once it is published.
+type Snapshot struct {
+	// Conf is the parsed configuration.
+	Conf *Conf
+	// Value is the struct returned from Watcher.New and filled from Conf; nil when New is nil.
+	Value interface{}
+}
+
+// fileState is the state of a watched file when it was last loaded.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// Watcher polls files and reloads the configuration when their contents change.
+//
+// Each reload parses and merges Files with Loader, fills a new struct returned from New, and publishes both as a
+// Snapshot.  Readers calling Snapshot() always see a complete configuration.  When a reload fails OnError is
+// called and the last good Snapshot is kept.
+//
+// A changed file is reloaded once it is unchanged between two consecutive polls so a file that is still being
+// written is not loaded; changes therefore take effect after one to two Intervals.
+//
+// Files included with include directives are not watched.
+type Watcher struct {
+	// Loader parses and merges Files in order; when Loader.Parser has no runes parser.DefaultParser is used.
+	Loader Loader
+	// Files to watch; they are loaded in order with Loader.Load.
+	Files []string
+	// Interval between polls; defaults to one second.
+	Interval time.Duration
+	// New returns a new pointer to struct that is filled from the configuration on each reload.
+	New func() interface{}
+	// FillOptions used to fill the value returned from New.
+	FillOptions FillOptions
+	// OnChange is called from the polling goroutine, or the goroutine calling Reload, after a new Snapshot is
+	// published.  It is called without holding the Watcher's lock so it may call any of the Watcher's methods.
+	OnChange func(snapshot *Snapshot)
+	// OnError is called from the polling goroutine, or the goroutine calling Reload, when a reload fails.  It is
+	// called without holding the Watcher's lock so it may call any of the Watcher's methods.
+	OnError func(err error)
+
+	snapshot atomic.Value
+
+	mut         sync.Mutex
+	states      map[string]fileState
+	pending     map[string]fileState
+	subscribers []chan *Snapshot
+	done        chan struct{}
+}
+
+// Start loads the configuration and begins polling Files for changes; an error is returned if the initial
+// load fails.
+func (me *Watcher) Start() error {
+	if me == nil {
+		return errors.NilReceiver()
+	}
+	me.mut.Lock()
+	defer me.mut.Unlock()
+	if me.done != nil {
+		return errors.AlreadyStarted()
+	}
+	if _, err := me.reload(); err != nil {
+		return err
+	}
+	interval := me.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	me.done = make(chan struct{})
+	go me.poll(interval, me.done)
+	return nil
+}
+
+// Stop ends polling and closes the channels returned from Subscribe; no Snapshot is published by polling once
+// Stop returns.  Stop does not wait for an OnChange or OnError call that is already running so it may be called
+// from either.
+func (me *Watcher) Stop() {
+	if me == nil {
+		return
+	}
+	me.mut.Lock()
+	defer me.mut.Unlock()
+	if me.done == nil {
+		return
+	}
+	close(me.done)
+	me.done = nil
+	for _, ch := range me.subscribers {
+		close(ch)
+	}
+	me.subscribers = nil
+}
+
+// Snapshot returns the current configuration or nil if it has not been loaded.
+func (me *Watcher) Snapshot() *Snapshot {
+	if me == nil {
+		return nil
+	}
+	snapshot, _ := me.snapshot.Load().(*Snapshot)
+	return snapshot
+}
+
+// Subscribe returns a channel that receives each new Snapshot.  Slow receivers only receive the most
+// recent Snapshot; the channel is closed by Stop.
+func (me *Watcher) Subscribe() <-chan *Snapshot {
+	ch := make(chan *Snapshot, 1)
+	me.mut.Lock()
+	defer me.mut.Unlock()
+	me.subscribers = append(me.subscribers, ch)
+	return ch
+}
+
+// Reload loads the configuration and publishes a new Snapshot even if Files have not changed.
+func (me *Watcher) Reload() error {
+	if me == nil {
+		return errors.NilReceiver()
+	}
+	me.mut.Lock()
+	snapshot, err := me.reload()
+	if err == nil {
+		me.publish(snapshot)
+	}
+	me.mut.Unlock()
+	me.callback(snapshot, err)
+	return err
+}
+
+// poll checks Files for changes every interval until done is closed.
+func (me *Watcher) poll(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			me.check(done)
+		}
+	}
+}
+
+// check reloads the configuration if any of Files have changed and every changed file is unchanged since the
+// previous poll; done is the channel of the polling goroutine and nothing is reloaded once it is closed.
+func (me *Watcher) check(done chan struct{}) {
+	me.mut.Lock()
+	snapshot, err := me.checkLocked(done)
+	me.mut.Unlock()
+	me.callback(snapshot, err)
+}
+
+// checkLocked reloads and publishes the configuration for check; both return values are nil when nothing
+// was reloaded.  The caller must hold mut.
+func (me *Watcher) checkLocked(done chan struct{}) (*Snapshot, error) {
+	if me.done != done {
+		return nil, nil
+	}
+	changed, settled := false, true
+	for _, name := range me.Files {
+		previous, ok := me.states[name]
+		info, err := os.Stat(name)
+		if err != nil && ok && previous.modTime.IsZero() {
+			// The file was already missing or unreadable when last loaded.
+			delete(me.pending, name)
+			continue
+		} else if err == nil && ok && info.ModTime().Equal(previous.modTime) && info.Size() == previous.size {
+			delete(me.pending, name)
+			continue
+		}
+		// The contents are compared only when the modification time or size differ.
+		state, err := readState(name)
+		if err == nil && ok && state.hash == previous.hash {
+			me.states[name] = state
+			delete(me.pending, name)
+			continue
+		}
+		if pending, ok := me.pending[name]; !ok || !pending.equal(state) {
+			// The file may still be written; it is reloaded if it is the same at the next poll.
+			settled = false
+		}
+		me.pending[name] = state
+		changed = true
+	}
+	if !changed || !settled {
+		return nil, nil
+	}
+	snapshot, err := me.reload()
+	if err != nil {
+		return nil, err
+	}
+	me.publish(snapshot)
+	return snapshot, nil
+}
+
+// reload loads the configuration and stores the new Snapshot; the file states are updated before loading
+// so changes made while loading are detected by the next poll.
+func (me *Watcher) reload() (*Snapshot, error) {
+	var err error
+	states := map[string]fileState{}
+	sources := make([]Source, len(me.Files))
+	for k, name := range me.Files {
+		state, stateErr := readState(name)
+		if stateErr != nil && err == nil {
+			err = stateErr
+		}
+		states[name], sources[k] = state, FileSource(name)
+	}
+	if me.states, me.pending = states, map[string]fileState{}; err != nil {
+		return nil, err
+	}
+	//
+	loader := me.Loader
+	if len(loader.Parser.Assign) == 0 {
+		loader.Parser = parser.DefaultParser
+	}
+	c, err := loader.Load(sources...)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{Conf: c}
+	if me.New != nil {
+		snapshot.Value = me.New()
+		if err = c.FillWith(snapshot.Value, me.FillOptions); err != nil {
+			return nil, err
+		}
+	}
+	me.snapshot.Store(snapshot)
+	return snapshot, nil
+}
+
+// publish sends the new Snapshot to subscribers; the caller must hold mut.
+func (me *Watcher) publish(snapshot *Snapshot) {
+	for _, ch := range me.subscribers {
+		// A pending Snapshot the receiver has not read is replaced.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+// callback calls OnError if err is not nil or OnChange if snapshot is not nil; the caller must not hold mut.
+func (me *Watcher) callback(snapshot *Snapshot, err error) {
+	if err != nil && me.OnError != nil {
+		me.OnError(err)
+	} else if err == nil && snapshot != nil && me.OnChange != nil {
+		me.OnChange(snapshot)
+	}
+}
+
+// equal returns true if the states are the same.
+func (me fileState) equal(other fileState) bool {
+	return me.modTime.Equal(other.modTime) && me.size == other.size && me.hash == other.hash
+}
+
+// readState returns the current state of the named file.
+func readState(name string) (fileState, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileState{}, errors.Go(err)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return fileState{}, errors.Go(err)
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(data)}, nil
+}
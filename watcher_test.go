@@ -0,0 +1,172 @@
+package conf_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nofeaturesonlybugs/conf"
+)
+
+func TestWatcher(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir, err := ioutil.TempDir("", "gotest")
+	chk.NoError(err)
+	defer os.RemoveAll(dir)
+	base, override := filepath.Join(dir, "base.conf"), filepath.Join(dir, "override.conf")
+	// write replaces the file contents and advances its modification time; the file is renamed into place so
+	// polling never sees it partially written.
+	mtime := time.Now()
+	write := func(name, contents string) {
+		tmp := name + ".tmp"
+		chk.NoError(ioutil.WriteFile(tmp, []byte(contents), 0644))
+		mtime = mtime.Add(time.Second)
+		chk.NoError(os.Chtimes(tmp, mtime, mtime))
+		chk.NoError(os.Rename(tmp, name))
+	}
+	write(base, "name = base\nport = 80\n")
+	write(override, "port = 8080\n")
+	//
+	type T struct {
+		Name string `conf:"name"`
+		Port int    `conf:"port"`
+	}
+	errs := make(chan error, 10)
+	w := &conf.Watcher{
+		Files:       []string{base, override},
+		Interval:    5 * time.Millisecond,
+		New:         func() interface{} { return &T{} },
+		FillOptions: conf.FillOptions{Tag: "conf"},
+		OnError:     func(err error) { errs <- err },
+	}
+	chk.Nil(w.Snapshot())
+	changes := w.Subscribe()
+	chk.NoError(w.Start())
+	defer w.Stop()
+	chk.Error(w.Start())
+	chk.Equal(&T{Name: "base", Port: 8080}, w.Snapshot().Value)
+	//
+	next := func() *conf.Snapshot {
+		select {
+		case snapshot := <-changes:
+			return snapshot
+		case <-time.After(5 * time.Second):
+			chk.Fail("timeout waiting for change")
+			return nil
+		}
+	}
+	{
+		write(override, "port = 9090\n")
+		snapshot := next()
+		chk.Equal(&T{Name: "base", Port: 9090}, snapshot.Value)
+		chk.Equal(snapshot, w.Snapshot())
+		port, err := snapshot.Conf.GetInt("", "port")
+		chk.NoError(err)
+		chk.Equal(9090, port)
+	}
+	{
+		// Touching a file without changing it does not reload.
+		previous := w.Snapshot()
+		write(base, "name = base\nport = 80\n")
+		time.Sleep(50 * time.Millisecond)
+		chk.True(previous == w.Snapshot())
+	}
+	{
+		// Parse errors keep the last good configuration.
+		previous := w.Snapshot()
+		write(base, "name = 'unterminated\n")
+		select {
+		case err := <-errs:
+			chk.Error(err)
+		case <-time.After(5 * time.Second):
+			chk.Fail("timeout waiting for error")
+		}
+		chk.True(previous == w.Snapshot())
+		write(base, "name = fixed\n")
+		chk.Equal(&T{Name: "fixed", Port: 9090}, next().Value)
+	}
+	{
+		// A file written in place is loaded once it is unchanged between two polls rather than partially written.
+		chk.NoError(ioutil.WriteFile(base, []byte("name = par\n"), 0644))
+		chk.NoError(ioutil.WriteFile(base, []byte("name = partial\n"), 0644))
+		chk.Equal(&T{Name: "partial", Port: 9090}, next().Value)
+		time.Sleep(50 * time.Millisecond)
+		chk.Len(changes, 0)
+		write(base, "name = fixed\n")
+		chk.Equal(&T{Name: "fixed", Port: 9090}, next().Value)
+	}
+	{
+		// Forced reload.
+		chk.NoError(w.Reload())
+		chk.Equal(&T{Name: "fixed", Port: 9090}, next().Value)
+		chk.NoError(os.Remove(override))
+		chk.Error(w.Reload())
+		chk.Equal(&T{Name: "fixed", Port: 9090}, w.Snapshot().Value)
+	}
+	w.Stop()
+	_, ok := <-changes
+	chk.False(ok)
+	w.Stop()
+	//
+	chk.Error((&conf.Watcher{Files: []string{filepath.Join(dir, "missing.conf")}}).Start())
+}
+
+func TestWatcherCallbacks(t *testing.T) {
+	chk := assert.New(t)
+	//
+	dir, err := ioutil.TempDir("", "gotest")
+	chk.NoError(err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.conf")
+	chk.NoError(ioutil.WriteFile(name, []byte("name = first\n"), 0644))
+	//
+	// Callbacks may call the Watcher's methods.
+	calls := make(chan string, 10)
+	var w *conf.Watcher
+	failures := 0
+	w = &conf.Watcher{
+		Files:    []string{name},
+		Interval: 5 * time.Millisecond,
+		OnChange: func(snapshot *conf.Snapshot) {
+			w.Subscribe()
+			chk.True(snapshot == w.Snapshot())
+			w.Stop()
+			calls <- "change"
+		},
+		OnError: func(err error) {
+			chk.Error(err)
+			calls <- "error"
+			if failures++; failures == 1 {
+				// Reload calls OnError again.
+				chk.Error(w.Reload())
+			}
+		},
+	}
+	changes := w.Subscribe()
+	chk.NoError(w.Start())
+	defer w.Stop()
+	//
+	next := func() string {
+		select {
+		case call := <-calls:
+			return call
+		case <-time.After(5 * time.Second):
+			chk.Fail("timeout waiting for callback")
+			return ""
+		}
+	}
+	chk.NoError(os.Remove(name))
+	chk.Equal("error", next())
+	chk.Equal("error", next())
+	chk.NoError(ioutil.WriteFile(name, []byte("name = second\n"), 0644))
+	chk.Equal("change", next())
+	_, ok := <-changes
+	chk.True(ok)
+	_, ok = <-changes
+	chk.False(ok)
+}